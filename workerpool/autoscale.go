@@ -0,0 +1,83 @@
+package workerpool
+
+import "time"
+
+// Autoscale configures the controller installed by WithAutoscale.
+type Autoscale struct {
+	// Min and Max bound the number of workers.
+	Min, Max int
+	// Interval is how often the pool is sampled. Defaults to one second.
+	Interval time.Duration
+	// TargetWait is the average queue wait above which the pool grows
+	// even if the queue is short. Zero means only queue length counts.
+	TargetWait time.Duration
+}
+
+// WithAutoscale resizes the pool between a.Min and a.Max workers, for
+// bursty traffic where a fixed size is either too small at the peak or
+// wasteful in between. Every a.Interval it looks at the last interval:
+//
+//   - if tasks are waiting and either there is more than one queued task
+//     per worker, or the average wait exceeded a.TargetWait, the pool
+//     doubles;
+//   - if nothing is waiting, workers were busy less than half the time and
+//     at most half of them are busy now, the pool sheds a quarter of its
+//     workers, or one if it has fewer than four.
+//
+// Growing fast and shrinking slowly keeps it from flapping. The size passed
+// to New is the starting point, clamped to the bounds.
+func WithAutoscale(a Autoscale) Option {
+	if a.Min <= 0 || a.Max < a.Min {
+		panic("workerpool: autoscale bounds must satisfy 0 < Min <= Max")
+	}
+	if a.Interval <= 0 {
+		a.Interval = time.Second
+	}
+	return func(c *config) { c.autoscale = &a }
+}
+
+func (p *Pool) autoscale(a Autoscale) {
+	t := time.NewTicker(a.Interval)
+	defer t.Stop()
+
+	last := p.Stats()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		s := p.Stats()
+		if n := a.next(last, s); n != s.Workers {
+			p.Resize(n)
+		}
+		last = s
+	}
+}
+
+// next decides the new size from two consecutive samples.
+func (a Autoscale) next(last, s Stats) int {
+	n := s.Workers
+
+	var avgWait time.Duration
+	if done := s.Completed - last.Completed; done > 0 {
+		avgWait = (s.WaitTime - last.WaitTime) / time.Duration(done)
+	}
+	busy := float64(s.RunTime-last.RunTime) / float64(a.Interval*time.Duration(n))
+
+	switch {
+	case s.Queued > 0 && (s.Queued > n || a.TargetWait > 0 && avgWait > a.TargetWait):
+		n *= 2
+	case s.Queued == 0 && busy < 0.5 && s.Busy <= n/2:
+		n -= max(n/4, 1)
+	}
+
+	if n < a.Min {
+		n = a.Min
+	}
+	if n > a.Max {
+		n = a.Max
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package workerpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoscaleNext(t *testing.T) {
+	a := Autoscale{Min: 2, Max: 16, Interval: time.Second, TargetWait: 100 * time.Millisecond}
+	tests := []struct {
+		name string
+		last Stats
+		s    Stats
+		want int
+	}{
+		{
+			name: "long queue grows",
+			s:    Stats{Workers: 4, Busy: 4, Queued: 5, RunTime: 4 * time.Second},
+			want: 8,
+		},
+		{
+			name: "long waits grow",
+			s:    Stats{Workers: 4, Busy: 4, Queued: 1, Completed: 2, WaitTime: time.Second, RunTime: 4 * time.Second},
+			want: 8,
+		},
+		{
+			name: "short queue and waits hold",
+			s:    Stats{Workers: 4, Busy: 4, Queued: 2, Completed: 10, WaitTime: 10 * time.Millisecond, RunTime: 4 * time.Second},
+			want: 4,
+		},
+		{
+			name: "waits count since the last sample",
+			last: Stats{Completed: 10, WaitTime: time.Minute},
+			s:    Stats{Workers: 4, Busy: 4, Queued: 2, Completed: 20, WaitTime: time.Minute, RunTime: 4 * time.Second},
+			want: 4,
+		},
+		{
+			name: "growth clamped to Max",
+			s:    Stats{Workers: 12, Busy: 12, Queued: 20, RunTime: 12 * time.Second},
+			want: 16,
+		},
+		{
+			name: "idle sheds a quarter",
+			s:    Stats{Workers: 16, Busy: 2, RunTime: 2 * time.Second},
+			want: 12,
+		},
+		{
+			name: "idle small pool sheds one",
+			s:    Stats{Workers: 4, Busy: 1},
+			want: 3,
+		},
+		{
+			name: "shrink clamped to Min",
+			s:    Stats{Workers: 2},
+			want: 2,
+		},
+		{
+			name: "busy over the interval holds",
+			s:    Stats{Workers: 8, Busy: 1, RunTime: 6 * time.Second},
+			want: 8,
+		},
+		{
+			name: "busy now holds",
+			s:    Stats{Workers: 8, Busy: 5, RunTime: time.Second},
+			want: 8,
+		},
+		{
+			name: "below Min is raised",
+			s:    Stats{Workers: 1, Busy: 1, RunTime: time.Second},
+			want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.next(tt.last, tt.s); got != tt.want {
+				t.Errorf("next = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
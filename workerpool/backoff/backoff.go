@@ -0,0 +1,61 @@
+// Package backoff computes the delays between retries.
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy returns how long to wait before retry number attempt, counting
+// from 1 for the first retry.
+type Policy interface {
+	Delay(attempt int) time.Duration
+}
+
+// Constant waits the same time before every retry.
+type Constant time.Duration
+
+// Delay implements Policy.
+func (c Constant) Delay(int) time.Duration {
+	return time.Duration(c)
+}
+
+// Exponential doubles the delay with every retry, starting at Base and
+// capped at Max (no cap if zero).
+//
+// Jitter is the fraction of each delay that is randomised, from 0 (none) to
+// 1 ("full jitter", anywhere between zero and the computed delay). Jitter
+// spreads out retries from many tasks that failed together.
+type Exponential struct {
+	Base   time.Duration
+	Max    time.Duration
+	Jitter float64
+}
+
+// Delay implements Policy.
+func (e Exponential) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	d := e.Base
+	for i := 1; i < attempt && d > 0; i++ {
+		if d > math.MaxInt64/2 {
+			d = math.MaxInt64 // saturate rather than overflow
+			break
+		}
+		d *= 2
+		if e.Max > 0 && d >= e.Max {
+			break
+		}
+	}
+	if e.Max > 0 && d > e.Max {
+		d = e.Max
+	}
+
+	if j := min(max(e.Jitter, 0), 1); j > 0 {
+		d -= time.Duration(j * rand.Float64() * float64(d))
+	}
+	return d
+}
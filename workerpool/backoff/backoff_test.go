@@ -0,0 +1,50 @@
+package backoff_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"workerpool/backoff"
+)
+
+func TestExponential(t *testing.T) {
+	tests := []struct {
+		name    string
+		e       backoff.Exponential
+		attempt int
+		want    time.Duration
+	}{
+		{"first", backoff.Exponential{Base: time.Second}, 1, time.Second},
+		{"below first", backoff.Exponential{Base: time.Second}, 0, time.Second},
+		{"doubles", backoff.Exponential{Base: time.Second}, 4, 8 * time.Second},
+		{"capped", backoff.Exponential{Base: time.Second, Max: 5 * time.Second}, 4, 5 * time.Second},
+		{"base over cap", backoff.Exponential{Base: time.Minute, Max: time.Second}, 1, time.Second},
+		{"zero base", backoff.Exponential{}, 10, 0},
+		{"overflow capped", backoff.Exponential{Base: time.Second, Max: time.Hour}, 100, time.Hour},
+		{"overflow uncapped", backoff.Exponential{Base: time.Second}, 100, math.MaxInt64},
+		{"huge base", backoff.Exponential{Base: math.MaxInt64/2 + 1}, 2, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.e.Delay(tt.attempt); got != tt.want {
+				t.Errorf("Delay(%d) = %v, want %v", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExponentialJitter(t *testing.T) {
+	e := backoff.Exponential{Base: time.Second, Jitter: 0.5}
+	for range 100 {
+		if d := e.Delay(3); d <= 2*time.Second || d > 4*time.Second {
+			t.Fatalf("Delay(3) = %v, want within (2s, 4s]", d)
+		}
+	}
+	e = backoff.Exponential{Base: time.Second, Jitter: 1}
+	for attempt := 1; attempt < 100; attempt++ {
+		if d := e.Delay(attempt); d < 0 {
+			t.Fatalf("Delay(%d) = %v, want no less than 0", attempt, d)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted is the cause of the cancellation of a batch that ran
+// out of budget.
+var ErrBudgetExhausted = errors.New("workerpool: batch budget exhausted")
+
+// Budget limits how much a batch may spend. Zero fields don't limit.
+type Budget struct {
+	// Wall is the time the whole batch may take, from the call to
+	// RunBatch.
+	Wall time.Duration
+
+	// Work is the total time the batch's tasks may spend running, summed
+	// over all of them: a stand-in for CPU time when the tasks are busy
+	// rather than waiting. It is checked as each task returns, so tasks
+	// already running when it runs out overshoot it by up to their own
+	// length before they are cancelled.
+	Work time.Duration
+}
+
+// BatchReport says how a batch went.
+type BatchReport struct {
+	Ran       int   // tasks that started
+	Skipped   []int // indexes of the tasks that never started
+	Err       error // errors of the tasks that ran, joined
+	Exhausted bool  // whether the budget ran out
+	Wall      time.Duration
+	Work      time.Duration
+}
+
+// RunBatch runs tasks on p within budget, on as many workers at a time as p
+// has, and waits for them. Once the budget is spent the tasks still
+// running are cancelled, with cause ErrBudgetExhausted, and those not yet
+// started are skipped and listed in the report; so are the rest if ctx is
+// done first. Called from a task of p, with its context, RunBatch runs the
+// tasks one at a time in place of that task's worker, as Scoped does.
+func (p *Pool) RunBatch(ctx context.Context, budget Budget, tasks []Task) BatchReport {
+	start := time.Now()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if budget.Wall > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, budget.Wall, ErrBudgetExhausted)
+		defer stop()
+	}
+
+	var (
+		mu      sync.Mutex
+		work    time.Duration
+		started = make([]bool, len(tasks))
+		errs    = make([]error, len(tasks))
+	)
+	scope := Scoped(ctx, p, max(p.Size(), 1))
+	for i, task := range tasks {
+		i, task := i, task
+		err := scope.Submit(func(ctx context.Context) error {
+			if ctx.Err() != nil {
+				return nil // picked up just as the batch ended
+			}
+			mu.Lock()
+			started[i] = true
+			mu.Unlock()
+
+			t := time.Now()
+			err := p.call(ctx, task)
+
+			mu.Lock()
+			errs[i] = err
+			work += time.Since(t)
+			if budget.Work > 0 && work >= budget.Work {
+				cancel(ErrBudgetExhausted)
+			}
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			break // the batch is over; the rest are skipped
+		}
+	}
+	// Tasks that never started don't have errors worth reporting, so
+	// the scope's own are ignored.
+	scope.Shutdown()
+
+	r := BatchReport{
+		Err:       errors.Join(errs...),
+		Exhausted: context.Cause(ctx) == ErrBudgetExhausted,
+		Wall:      time.Since(start),
+		Work:      work,
+	}
+	for i, ok := range started {
+		if ok {
+			r.Ran++
+		} else {
+			r.Skipped = append(r.Skipped, i)
+		}
+	}
+	return r
+}
@@ -0,0 +1,173 @@
+// Command burstbench simulates bursty task arrivals, the case the workerpool4
+// notes warn about, and compares a prespawned workerpool.Pool against
+// spinning up a goroutine per task. For every scenario and mode it records how
+// long each task waited between its scheduled arrival and the moment it
+// started running, and writes the distribution as CSV.
+//
+//	go run ./cmd/burstbench -workers 8 -work 2ms > waits.csv
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"workerpool"
+)
+
+// scenario describes an arrival pattern: bursts of size tasks, spread evenly
+// over spread, with gap between the start of one burst and the next.
+type scenario struct {
+	name   string
+	bursts int
+	size   int
+	spread time.Duration
+	gap    time.Duration
+}
+
+// mode runs one task per arrival and must not return before all of them
+// have finished.
+type mode struct {
+	name string
+	run  func(arrivals []time.Time, work time.Duration) []time.Duration
+}
+
+func main() {
+	workers := flag.Int("workers", 8, "number of prespawned workers")
+	work := flag.Duration("work", 2*time.Millisecond, "time each task spends working")
+	out := flag.String("out", "", "write the CSV here instead of stdout")
+	flag.Parse()
+
+	scenarios := []scenario{
+		// Steady keeps the workers around 80% busy.
+		{name: "steady", bursts: 1, size: 2000, spread: 2500 * *work / time.Duration(*workers)},
+		{name: "bursty", bursts: 20, size: 100, spread: *work, gap: 50 * *work},
+		{name: "spiky", bursts: 4, size: 1000, spread: 0, gap: 200 * *work},
+	}
+	modes := []mode{
+		{name: "prespawned", run: func(a []time.Time, w time.Duration) []time.Duration { return runPool(*workers, a, w) }},
+		{name: "on-demand", run: runGoroutines},
+		{name: "on-demand-bounded", run: func(a []time.Time, w time.Duration) []time.Duration { return runBounded(*workers, a, w) }},
+	}
+
+	f := os.Stdout
+	if *out != "" {
+		var err error
+		if f, err = os.Create(*out); err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+	}
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"scenario", "mode", "tasks", "mean_us", "p50_us", "p90_us", "p99_us", "max_us"})
+	for _, s := range scenarios {
+		for _, m := range modes {
+			waits := m.run(schedule(s), *work)
+			w.Write(row(s.name, m.name, waits))
+			w.Flush()
+		}
+	}
+	if err := w.Error(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// schedule returns the arrival time of every task in s, starting a little in
+// the future so setup cost doesn't count as waiting.
+func schedule(s scenario) []time.Time {
+	start := time.Now().Add(10 * time.Millisecond)
+	arrivals := make([]time.Time, 0, s.bursts*s.size)
+	for b := 0; b < s.bursts; b++ {
+		burst := start.Add(time.Duration(b) * (s.spread + s.gap))
+		for i := 0; i < s.size; i++ {
+			arrivals = append(arrivals, burst.Add(s.spread*time.Duration(i)/time.Duration(s.size)))
+		}
+	}
+	return arrivals
+}
+
+// produce calls submit for every arrival once its time has come.
+func produce(arrivals []time.Time, submit func(i int)) {
+	for i, at := range arrivals {
+		if d := time.Until(at); d > 0 {
+			time.Sleep(d)
+		}
+		submit(i)
+	}
+}
+
+func runPool(workers int, arrivals []time.Time, work time.Duration) []time.Duration {
+	waits := make([]time.Duration, len(arrivals))
+	p := workerpool.New(workers)
+	produce(arrivals, func(i int) {
+		p.Submit(func(context.Context) error {
+			waits[i] = time.Since(arrivals[i])
+			time.Sleep(work)
+			return nil
+		})
+	})
+	p.Shutdown()
+	return waits
+}
+
+func runGoroutines(arrivals []time.Time, work time.Duration) []time.Duration {
+	waits := make([]time.Duration, len(arrivals))
+	var wg sync.WaitGroup
+	produce(arrivals, func(i int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			waits[i] = time.Since(arrivals[i])
+			time.Sleep(work)
+		}()
+	})
+	wg.Wait()
+	return waits
+}
+
+// runBounded spawns a goroutine per task but caps how many may work at once,
+// which is what a fair comparison with a fixed number of workers needs.
+func runBounded(limit int, arrivals []time.Time, work time.Duration) []time.Duration {
+	waits := make([]time.Duration, len(arrivals))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	produce(arrivals, func(i int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			waits[i] = time.Since(arrivals[i])
+			time.Sleep(work)
+		}()
+	})
+	wg.Wait()
+	return waits
+}
+
+func row(scenario, mode string, waits []time.Duration) []string {
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+
+	var total time.Duration
+	for _, w := range waits {
+		total += w
+	}
+	pct := func(p float64) time.Duration {
+		return waits[int(p*float64(len(waits)-1))]
+	}
+	us := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Microsecond), 'f', 1, 64)
+	}
+	return []string{
+		scenario, mode, fmt.Sprint(len(waits)),
+		us(total / time.Duration(len(waits))), us(pct(0.5)), us(pct(0.9)), us(pct(0.99)), us(waits[len(waits)-1]),
+	}
+}
@@ -0,0 +1,119 @@
+// Command cancelbench measures how long a pool takes to get rid of a full
+// queue once the work in it is cancelled: the time from cancel() to Wait
+// returning, for queues of growing size and three ways of cancelling.
+//
+//	go run ./cmd/cancelbench -sizes 1000,10000,100000
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"workerpool"
+)
+
+// scenario queues n tasks behind a busy worker and returns the function that
+// cancels them all, and one that releases anything else it allocated.
+type scenario struct {
+	name  string
+	setup func(n int) (p *workerpool.Pool, cancel, release func())
+}
+
+var scenarios = []scenario{
+	{"pool context", func(n int) (*workerpool.Pool, func(), func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := workerpool.NewWithContext(ctx, 1)
+		fill(p, n, func(task workerpool.Task) { p.Submit(task) })
+		return p, cancel, func() {}
+	}},
+	{"shared task context", func(n int) (*workerpool.Pool, func(), func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := workerpool.New(1)
+		fill(p, n, func(task workerpool.Task) { p.SubmitContext(ctx, task) })
+		return p, cancel, func() {}
+	}},
+	{"context per task", func(n int) (*workerpool.Pool, func(), func()) {
+		parent, cancel := context.WithCancel(context.Background())
+		p := workerpool.New(1)
+		var cancels []context.CancelFunc
+		fill(p, n, func(task workerpool.Task) {
+			ctx, cancel := context.WithCancel(parent)
+			cancels = append(cancels, cancel)
+			p.SubmitContext(ctx, task)
+		})
+		return p, cancel, func() {
+			for _, cancel := range cancels {
+				cancel()
+			}
+		}
+	}},
+}
+
+// fill occupies the pool's only worker until the tasks are cancelled, then
+// queues n more behind it with submit.
+func fill(p *workerpool.Pool, n int, submit func(workerpool.Task)) {
+	started := make(chan struct{})
+	submit(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	<-started
+	for i := 0; i < n; i++ {
+		submit(func(context.Context) error { return nil })
+	}
+}
+
+func main() {
+	sizes := flag.String("sizes", "1000,10000,100000", "comma-separated queue sizes")
+	runs := flag.Int("runs", 5, "runs per measurement; the median is reported")
+	flag.Parse()
+
+	var ns []int
+	for _, s := range strings.Split(*sizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			log.Fatalf("bad size %q", s)
+		}
+		ns = append(ns, n)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(w, "scenario\tqueued\tcancel to idle\tper task\t\n")
+	for _, sc := range scenarios {
+		for _, n := range ns {
+			d := median(*runs, func() time.Duration {
+				p, cancel, release := sc.setup(n)
+				start := time.Now()
+				cancel()
+				p.Wait()
+				d := time.Since(start)
+				p.Shutdown()
+				release()
+				return d
+			})
+			fmt.Fprintf(w, "%s\t%d\t%v\t%v\t\n", sc.name, n, d.Round(time.Microsecond), d/time.Duration(n))
+		}
+	}
+	w.Flush()
+}
+
+func median(runs int, f func() time.Duration) time.Duration {
+	ds := make([]time.Duration, runs)
+	for i := range ds {
+		ds[i] = f()
+	}
+	for i := 1; i < len(ds); i++ {
+		for j := i; j > 0 && ds[j] < ds[j-1]; j-- {
+			ds[j], ds[j-1] = ds[j-1], ds[j]
+		}
+	}
+	return ds[len(ds)/2]
+}
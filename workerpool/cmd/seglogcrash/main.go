@@ -0,0 +1,43 @@
+// Command seglogcrash checks seglog's crash guarantees, as listed in
+// internal/crashtest, over many logs and crashes. It exits non-zero on the
+// first violation.
+//
+//	go run ./cmd/seglogcrash -runs 200 -crashes 50
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"workerpool/internal/crashtest"
+)
+
+func main() {
+	runs := flag.Int("runs", 100, "number of independent logs to torture")
+	crashes := flag.Int("crashes", 30, "crash/reopen cycles per run")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed")
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.Printf("seed %d", *seed)
+	rng := rand.New(rand.NewSource(*seed))
+
+	for run := 0; run < *runs; run++ {
+		if err := torture(rng, *crashes); err != nil {
+			log.Fatalf("run %d: %v", run, err)
+		}
+	}
+	log.Printf("ok: %d runs, %d crashes each", *runs, *crashes)
+}
+
+func torture(rng *rand.Rand, crashes int) error {
+	dir, err := os.MkdirTemp("", "seglogcrash")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	return crashtest.Torture(dir, rng, crashes)
+}
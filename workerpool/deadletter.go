@@ -0,0 +1,23 @@
+package workerpool
+
+// DeadLetter describes a task that failed for good: it returned an error,
+// or panicked, on its last allowed attempt.
+type DeadLetter struct {
+	Task     Task
+	Priority int
+	Attempts int   // times the task was run
+	Err      error // the error of the last attempt
+}
+
+// DeadLetterHandler is called on the worker's goroutine with each task that
+// failed for good. It may resubmit the task, log it or persist it; sending
+// it on a channel gives the classic dead-letter queue.
+type DeadLetterHandler func(DeadLetter)
+
+// deadLocked reports whether j, about to settle with err, is a dead letter.
+// Tasks that fail because they were cancelled were abandoned rather than
+// failed, and Future callers already hold their error. p.mu must be held.
+func (p *Pool) deadLocked(j *Job, err error) bool {
+	return p.onDead != nil && err != nil && !j.silent &&
+		p.ctx.Err() == nil && j.ctx.Err() == nil
+}
@@ -0,0 +1,67 @@
+package workerpool
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// DebugLevel is how much the pool does to help debug it; see SetDebug.
+type DebugLevel int32
+
+const (
+	// DebugOff is the default: nothing beyond the pool's usual logging.
+	DebugOff DebugLevel = iota
+	// DebugEvents logs each task's submission, every start and end of a
+	// run and every retry at slog.LevelInfo, with the task's ID.
+	DebugEvents
+	// DebugChecks also checks the pool's bookkeeping after every run,
+	// logging whatever doesn't add up at slog.LevelError.
+	DebugChecks
+)
+
+// SetDebug changes the pool's debug level while it runs, so a production
+// incident can be looked into without a restart and the extra logging
+// turned off again afterwards. At DebugOff the cost is an atomic load or
+// two per task.
+func (p *Pool) SetDebug(level DebugLevel) {
+	p.debug.Store(int32(level))
+}
+
+// debugging reports whether the pool's debug level is at least level.
+func (p *Pool) debugging(level DebugLevel) bool {
+	return DebugLevel(p.debug.Load()) >= level
+}
+
+// event logs msg if the pool is logging events.
+func (p *Pool) event(ctx context.Context, msg string, args ...any) {
+	if p.debugging(DebugEvents) {
+		p.log(ctx, slog.LevelInfo, msg, args...)
+	}
+}
+
+// check logs the pool's counters that contradict each other.
+func (p *Pool) check(ctx context.Context) {
+	p.mu.Lock()
+	var broken []string
+	fail := func(format string, args ...any) {
+		broken = append(broken, fmt.Sprintf(format, args...))
+	}
+	if p.busy < 0 || p.busy > p.running {
+		fail("busy workers %d out of 0..%d running", p.busy, p.running)
+	}
+	if p.busyLow < 0 || p.busyLow > p.busy {
+		fail("busy unreserved workers %d out of 0..%d busy", p.busyLow, p.busy)
+	}
+	if p.taken < 0 || p.slots > 0 && p.taken > p.slots {
+		fail("slots taken %d out of 0..%d", p.taken, p.slots)
+	}
+	if n := p.queue.Len() + p.busy + len(p.retries); p.pending != n {
+		fail("pending tasks %d, but %d queued, running or retrying", p.pending, n)
+	}
+	p.mu.Unlock()
+
+	for _, b := range broken {
+		p.log(ctx, slog.LevelError, "workerpool: bookkeeping is off", "check", b)
+	}
+}
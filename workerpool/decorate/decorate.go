@@ -0,0 +1,58 @@
+// Package decorate layers cross-cutting behaviour over any
+// workerpool.Submitter, a pool or another decorator, without new pool
+// options:
+//
+//	s := decorate.Chain(pool,
+//		decorate.Authorize(checkCaller),
+//		decorate.RateLimit(100, 10),
+//		decorate.Instrument(&metrics),
+//	)
+//
+// Decorators turn Submit into SubmitContext with context.Background(), so
+// they only have one path to get right.
+package decorate
+
+import (
+	"context"
+
+	"workerpool"
+)
+
+// Decorator wraps a Submitter in another that adds some behaviour.
+type Decorator func(next workerpool.Submitter) workerpool.Submitter
+
+// Chain wraps s in decorators, the first outermost: the first decorator
+// sees a submission first and the task last.
+func Chain(s workerpool.Submitter, decorators ...Decorator) workerpool.Submitter {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		s = decorators[i](s)
+	}
+	return s
+}
+
+// Func adapts a function to a Submitter, for writing decorators.
+type Func func(ctx context.Context, task workerpool.Task) error
+
+// Submit calls f with context.Background().
+func (f Func) Submit(task workerpool.Task) error {
+	return f(context.Background(), task)
+}
+
+// SubmitContext calls f.
+func (f Func) SubmitContext(ctx context.Context, task workerpool.Task) error {
+	return f(ctx, task)
+}
+
+// Authorize rejects a submission, with check's error, unless check accepts
+// the submitting context; typically it looks for credentials stored there
+// by the caller's middleware.
+func Authorize(check func(ctx context.Context) error) Decorator {
+	return func(next workerpool.Submitter) workerpool.Submitter {
+		return Func(func(ctx context.Context, task workerpool.Task) error {
+			if err := check(ctx); err != nil {
+				return err
+			}
+			return next.SubmitContext(ctx, task)
+		})
+	}
+}
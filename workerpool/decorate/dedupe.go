@@ -0,0 +1,82 @@
+package decorate
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"workerpool"
+)
+
+// ErrDuplicate is returned by a Dedupe decorator for a submission whose key
+// is already queued or running.
+var ErrDuplicate = errors.New("decorate: duplicate task")
+
+type keyKey struct{}
+
+// WithKey returns a copy of ctx carrying the deduplication key of the task
+// it is submitted with.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// Key returns the deduplication key stored in ctx by WithKey.
+func Key(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(keyKey{}).(string)
+	return key, ok
+}
+
+// Dedupe rejects with ErrDuplicate a task submitted with the same key,
+// given by WithKey, as one that hasn't finished yet. Tasks without a key
+// are passed through. To give duplicates the first task's result instead,
+// use a Group.
+//
+// A key is released when its task returns, or when the submitting context
+// is done before the task starts. A pool that throws tasks away for other
+// reasons, such as the DropNewest and DropOldest overflow policies, would
+// keep their keys taken for good, so don't dedupe in front of one.
+func Dedupe() Decorator {
+	return func(next workerpool.Submitter) workerpool.Submitter {
+		var (
+			mu      sync.Mutex
+			pending = make(map[string]struct{})
+		)
+		return Func(func(ctx context.Context, task workerpool.Task) error {
+			key, ok := Key(ctx)
+			if !ok {
+				return next.SubmitContext(ctx, task)
+			}
+
+			mu.Lock()
+			if _, dup := pending[key]; dup {
+				mu.Unlock()
+				return ErrDuplicate
+			}
+			pending[key] = struct{}{}
+			mu.Unlock()
+
+			var once sync.Once
+			release := func() {
+				once.Do(func() {
+					mu.Lock()
+					delete(pending, key)
+					mu.Unlock()
+				})
+			}
+			stop := context.AfterFunc(ctx, release)
+
+			err := next.SubmitContext(ctx, func(ctx context.Context) error {
+				// Once running, the task holds the key until it
+				// returns, even if it ignores cancellation.
+				stop()
+				defer release()
+				return task(ctx)
+			})
+			if err != nil {
+				stop()
+				release()
+			}
+			return err
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package decorate
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"workerpool"
+)
+
+// Metrics counts what goes through an Instrument decorator. It is safe to
+// read while tasks run; the zero value is ready to use.
+type Metrics struct {
+	Submitted atomic.Uint64 // submissions accepted by the next Submitter
+	Rejected  atomic.Uint64 // submissions it returned an error for
+	Succeeded atomic.Uint64 // tasks that returned nil
+	Failed    atomic.Uint64 // tasks that returned an error or panicked
+	Running   atomic.Int64  // tasks running now
+	RunTime   atomic.Int64  // total time spent running tasks, in nanoseconds
+}
+
+// Instrument records submissions and task outcomes in m. Sharing m between
+// several decorated Submitters adds their counts up.
+func Instrument(m *Metrics) Decorator {
+	return func(next workerpool.Submitter) workerpool.Submitter {
+		return Func(func(ctx context.Context, task workerpool.Task) error {
+			err := next.SubmitContext(ctx, func(ctx context.Context) (err error) {
+				m.Running.Add(1)
+				start := time.Now()
+				failed := true
+				defer func() {
+					m.RunTime.Add(int64(time.Since(start)))
+					m.Running.Add(-1)
+					if failed {
+						m.Failed.Add(1)
+					} else {
+						m.Succeeded.Add(1)
+					}
+				}()
+
+				err = task(ctx)
+				failed = err != nil
+				return err
+			})
+			if err != nil {
+				m.Rejected.Add(1)
+			} else {
+				m.Submitted.Add(1)
+			}
+			return err
+		})
+	}
+}
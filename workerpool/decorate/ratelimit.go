@@ -0,0 +1,65 @@
+package decorate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"workerpool"
+)
+
+// RateLimit lets submissions through at rps a second on average, with
+// bursts of up to burst, making Submit wait its turn. SubmitContext gives up
+// with ctx's error if ctx is done first.
+//
+// This limits how fast work is handed off; to limit how fast the pool
+// starts work that is already queued, use workerpool.WithRateLimit.
+func RateLimit(rps float64, burst int) Decorator {
+	if rps <= 0 || burst < 1 {
+		panic("decorate: rate limit needs rps > 0 and burst >= 1")
+	}
+	return func(next workerpool.Submitter) workerpool.Submitter {
+		l := &bucket{rate: rps, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+		return Func(func(ctx context.Context, task workerpool.Task) error {
+			if err := l.wait(ctx); err != nil {
+				return err
+			}
+			return next.SubmitContext(ctx, task)
+		})
+	}
+}
+
+// bucket is a token bucket whose tokens can be reserved ahead of time, so
+// waiting callers are let through in the order they arrived.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64 // negative while there are reservations outstanding
+	last   time.Time
+}
+
+func (b *bucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	d := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++ // give the reservation back
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
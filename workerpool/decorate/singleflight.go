@@ -0,0 +1,131 @@
+package decorate
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"workerpool"
+)
+
+var errPanicked = errors.New("decorate: shared task panicked")
+
+// Group merges concurrent calls for the same key into one task, like
+// golang.org/x/sync/singleflight, except that cancellation is per caller:
+//
+//   - A caller whose context is done returns at once with the context's
+//     error, and the others keep waiting for the result. It makes no
+//     difference whether it was the caller that started the task.
+//   - The task runs with the starting caller's context values but none of
+//     its cancellation or deadline, and its context is cancelled only when
+//     every caller waiting for it has gone. The pool then discards it if
+//     it is still queued.
+//   - Once every caller has gone, the key is free again: the next call
+//     starts a fresh task rather than joining the abandoned one.
+//
+// As with Dedupe, a Submitter that throws tasks away after accepting them
+// leaves their callers waiting until their contexts are done.
+type Group[R any] struct {
+	s workerpool.Submitter
+
+	mu    sync.Mutex
+	calls map[string]*call[R]
+}
+
+// call is a task running, or queued, for the callers of one key.
+type call[R any] struct {
+	done    chan struct{}
+	value   R
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// NewGroup returns a Group that submits its tasks to s.
+func NewGroup[R any](s workerpool.Submitter) *Group[R] {
+	return &Group[R]{s: s, calls: make(map[string]*call[R])}
+}
+
+// Do submits fn under key unless a task for key is already queued or
+// running, and waits for the result, or for ctx to be done. shared reports
+// whether the task was started by another caller. If the task cannot be
+// submitted, every caller waiting for it gets the Submitter's error.
+func (g *Group[R]) Do(ctx context.Context, key string, fn func(ctx context.Context) (R, error)) (value R, err error, shared bool) {
+	if err := ctx.Err(); err != nil {
+		return value, err, false
+	}
+
+	var runCtx context.Context
+	g.mu.Lock()
+	c, shared := g.calls[key]
+	if shared {
+		c.waiters++
+	} else {
+		c = &call[R]{done: make(chan struct{}), waiters: 1}
+		runCtx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		g.calls[key] = c
+	}
+	g.mu.Unlock()
+
+	if !shared {
+		g.start(runCtx, key, c, fn)
+	}
+
+	select {
+	case <-c.done:
+		return c.value, c.err, shared
+	case <-ctx.Done():
+		g.leave(key, c)
+		return value, ctx.Err(), shared
+	}
+}
+
+// start submits the task of c, to run with ctx.
+func (g *Group[R]) start(ctx context.Context, key string, c *call[R], fn func(ctx context.Context) (R, error)) {
+	err := g.s.SubmitContext(ctx, func(ctx context.Context) error {
+		returned := false
+		defer func() {
+			if !returned {
+				var zero R
+				g.finish(key, c, zero, errPanicked)
+			}
+		}()
+
+		value, err := fn(ctx)
+		returned = true
+		g.finish(key, c, value, err)
+		return err
+	})
+	if err != nil {
+		var zero R
+		g.finish(key, c, zero, err)
+	}
+}
+
+// finish hands the outcome of c to its callers.
+func (g *Group[R]) finish(key string, c *call[R], value R, err error) {
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+
+	c.value, c.err = value, err
+	close(c.done)
+	c.cancel()
+}
+
+// leave gives up one caller's wait for c, cancelling the task if it was
+// the last.
+func (g *Group[R]) leave(key string, c *call[R]) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c.waiters--; c.waiters > 0 {
+		return
+	}
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	c.cancel()
+}
@@ -0,0 +1,181 @@
+package decorate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"workerpool"
+	"workerpool/pooltest"
+)
+
+type result struct {
+	value  int
+	err    error
+	shared bool
+}
+
+// do calls g.Do in the background and returns where its result will go.
+func do(g *Group[int], ctx context.Context, key string, fn func(context.Context) (int, error)) <-chan result {
+	ch := make(chan result, 1)
+	go func() {
+		v, err, shared := g.Do(ctx, key, fn)
+		ch <- result{v, err, shared}
+	}()
+	return ch
+}
+
+// waiters waits for n callers to be waiting on key.
+func waiters(t *testing.T, g *Group[int], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		c := g.calls[key]
+		ok := c != nil && c.waiters == n
+		g.mu.Unlock()
+		if ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers never joined %q", n, key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// submitted waits for rec to have recorded n tasks.
+func submitted(t *testing.T, rec *pooltest.Recorder, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for rec.Len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d submissions, want %d", rec.Len(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func recv(t *testing.T, ch <-chan result) result {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do did not return")
+		return result{}
+	}
+}
+
+func answer(context.Context) (int, error) { return 42, nil }
+
+func TestGroupLeaderCancelled(t *testing.T) {
+	rec := &pooltest.Recorder{}
+	g := NewGroup[int](rec)
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leader := do(g, leaderCtx, "k", answer)
+	waiters(t, g, "k", 1)
+	follower := do(g, context.Background(), "k", answer)
+	waiters(t, g, "k", 2)
+
+	cancel()
+	if r := recv(t, leader); !errors.Is(r.err, context.Canceled) || r.shared {
+		t.Errorf("cancelled leader got %+v, want context.Canceled, not shared", r)
+	}
+	if err := rec.Submissions()[0].Ctx.Err(); err != nil {
+		t.Fatalf("the task's context was cancelled with its leader: %v", err)
+	}
+	rec.RunAll()
+	if r := recv(t, follower); r.err != nil || r.value != 42 || !r.shared {
+		t.Errorf("follower got %+v, want 42, shared", r)
+	}
+}
+
+func TestGroupAllWaitersLeave(t *testing.T) {
+	rec := &pooltest.Recorder{}
+	g := NewGroup[int](rec)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	first := do(g, ctx1, "k", answer)
+	waiters(t, g, "k", 1)
+	second := do(g, ctx2, "k", answer)
+	waiters(t, g, "k", 2)
+
+	cancel1()
+	recv(t, first)
+	taskCtx := rec.Submissions()[0].Ctx
+	if taskCtx.Err() != nil {
+		t.Fatal("the task was cancelled while a caller was still waiting")
+	}
+	cancel2()
+	recv(t, second)
+	if taskCtx.Err() == nil {
+		t.Error("the task was not cancelled once every caller had gone")
+	}
+
+	// The key is free: the next caller starts a fresh task.
+	next := do(g, context.Background(), "k", answer)
+	submitted(t, rec, 2)
+	rec.RunAll()
+	if r := recv(t, next); r.err != nil || r.value != 42 || r.shared {
+		t.Errorf("next caller got %+v, want 42, not shared", r)
+	}
+}
+
+// gatedSubmitter fails every submission, but not until release is closed.
+type gatedSubmitter struct {
+	release chan struct{}
+	err     error
+}
+
+func (s *gatedSubmitter) Submit(task workerpool.Task) error {
+	return s.SubmitContext(context.Background(), task)
+}
+
+func (s *gatedSubmitter) SubmitContext(context.Context, workerpool.Task) error {
+	<-s.release
+	return s.err
+}
+
+func TestGroupSubmitError(t *testing.T) {
+	errFull := errors.New("queue full")
+	s := &gatedSubmitter{release: make(chan struct{}), err: errFull}
+	g := NewGroup[int](s)
+
+	var results []<-chan result
+	for i := 1; i <= 3; i++ {
+		results = append(results, do(g, context.Background(), "k", answer))
+		waiters(t, g, "k", i)
+	}
+	close(s.release)
+	for i, ch := range results {
+		if r := recv(t, ch); !errors.Is(r.err, errFull) {
+			t.Errorf("caller %d got %v, want the submit error", i, r.err)
+		}
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	rec := &pooltest.Recorder{}
+	g := NewGroup[int](rec)
+
+	leader := do(g, context.Background(), "k", func(context.Context) (int, error) {
+		panic("boom")
+	})
+	waiters(t, g, "k", 1)
+	follower := do(g, context.Background(), "k", answer)
+	waiters(t, g, "k", 2)
+	submitted(t, rec, 1)
+
+	if o := rec.RunAll(); len(o) != 1 || !o[0].Panicked {
+		t.Fatalf("outcomes %+v, want the one task to panic", o)
+	}
+	for _, ch := range []<-chan result{leader, follower} {
+		if r := recv(t, ch); !errors.Is(r.err, errPanicked) {
+			t.Errorf("got %v, want errPanicked", r.err)
+		}
+	}
+}
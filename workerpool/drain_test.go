@@ -0,0 +1,129 @@
+package workerpool_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"workerpool"
+)
+
+// blocker occupies a worker until its context is done or release is
+// closed, and reports the cause it was cancelled with.
+type blocker struct {
+	started chan struct{}
+	release chan struct{}
+	cause   chan error
+}
+
+func newBlocker() *blocker {
+	return &blocker{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+		cause:   make(chan error, 1),
+	}
+}
+
+func (b *blocker) task(ctx context.Context) error {
+	close(b.started)
+	select {
+	case <-ctx.Done():
+		b.cause <- context.Cause(ctx)
+	case <-b.release:
+		b.cause <- nil
+	}
+	return nil
+}
+
+func TestDrain(t *testing.T) {
+	p := workerpool.New(2)
+	var mu sync.Mutex
+	ran := 0
+	for range 10 {
+		p.Submit(func(context.Context) error {
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			ran++
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain = %v", err)
+	}
+	if ran != 10 {
+		t.Errorf("%d tasks ran before Drain returned, want 10", ran)
+	}
+	if err := p.Submit(func(context.Context) error { return nil }); !errors.Is(err, workerpool.ErrClosed) {
+		t.Errorf("Submit after Drain = %v, want ErrClosed", err)
+	}
+}
+
+func TestDrainDeadline(t *testing.T) {
+	p := workerpool.New(1)
+	b := newBlocker()
+	p.Submit(b.task)
+	<-b.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain = %v, want DeadlineExceeded", err)
+	}
+
+	// The pool carries on draining.
+	close(b.release)
+	if err := p.Drain(context.Background()); err != nil {
+		t.Errorf("second Drain = %v", err)
+	}
+}
+
+func TestAbort(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		submit func(p *workerpool.Pool, task workerpool.Task) error
+	}{
+		{"Submit", func(p *workerpool.Pool, task workerpool.Task) error { return p.Submit(task) }},
+		{"SubmitContext", func(p *workerpool.Pool, task workerpool.Task) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			return p.SubmitContext(ctx, task)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := workerpool.New(1)
+			b := newBlocker()
+			tc.submit(p, b.task)
+			<-b.started
+
+			var order []int
+			for i := range 5 {
+				p.Submit(func(context.Context) error {
+					order = append(order, i)
+					return nil
+				})
+			}
+			f := workerpool.SubmitFuture(p, func(context.Context) (int, error) { return 1, nil })
+
+			backlog := p.Abort()
+			if cause := <-b.cause; !errors.Is(cause, workerpool.ErrAborted) {
+				t.Errorf("running task cancelled with cause %v, want ErrAborted", cause)
+			}
+			if _, err := f.Await(context.Background()); !errors.Is(err, workerpool.ErrAborted) {
+				t.Errorf("queued Future = %v, want ErrAborted", err)
+			}
+			for _, task := range backlog {
+				task(context.Background())
+			}
+			if want := []int{0, 1, 2, 3, 4}; !slices.Equal(order, want) {
+				t.Errorf("backlog ran in order %v, want %v", order, want)
+			}
+			if err := p.Submit(func(context.Context) error { return nil }); !errors.Is(err, workerpool.ErrClosed) {
+				t.Errorf("Submit after Abort = %v, want ErrClosed", err)
+			}
+		})
+	}
+}
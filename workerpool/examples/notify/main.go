@@ -0,0 +1,153 @@
+// Command notify is an example of scatter-gather on a workerpool.Pool: each
+// event is sent out through several channels (email, SMS, push) at once,
+// each with its own timeout, and the sender reports which channels failed
+// instead of giving up on the whole event at the first error.
+//
+// The adapters are stand-ins that sleep and sometimes fail; swap in real
+// clients to use the same shape.
+//
+//	go run ./examples/notify -events 5
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"workerpool"
+)
+
+// Event is something a user should be told about.
+type Event struct {
+	User    string
+	Message string
+}
+
+// Adapter delivers events through one channel.
+type Adapter struct {
+	Name    string
+	Timeout time.Duration // how long a single delivery may take
+	Send    func(ctx context.Context, e Event) error
+}
+
+// DeliveryError records a channel that failed to deliver an event.
+type DeliveryError struct {
+	Channel string
+	Err     error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Channel + ": " + e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Notifier sends events through all of its adapters.
+type Notifier struct {
+	pool     *workerpool.Pool
+	adapters []Adapter
+}
+
+// Notify sends e through every adapter concurrently and waits for all of
+// them. The error joins a *DeliveryError for each adapter that failed; the
+// others delivered.
+//
+// Each event gets a scoped pool, so its deliveries are waited for on their
+// own while sharing the workers of n.pool with every other event.
+func (n *Notifier) Notify(ctx context.Context, e Event) error {
+	scope := workerpool.Scoped(ctx, n.pool, len(n.adapters))
+	defer scope.Shutdown()
+
+	for _, a := range n.adapters {
+		a := a
+		err := scope.Submit(func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, a.Timeout)
+			defer cancel()
+			if err := a.Send(ctx, e); err != nil {
+				return &DeliveryError{Channel: a.Name, Err: err}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return scope.Wait()
+}
+
+// fake returns a stand-in adapter that takes up to latency to deliver and
+// fails with probability failRate.
+func fake(name string, timeout, latency time.Duration, failRate float64) Adapter {
+	return Adapter{
+		Name:    name,
+		Timeout: timeout,
+		Send: func(ctx context.Context, e Event) error {
+			select {
+			case <-time.After(time.Duration(rand.Int63n(int64(latency)))):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if rand.Float64() < failRate {
+				return errors.New("provider rejected the message")
+			}
+			return nil
+		},
+	}
+}
+
+func main() {
+	events := flag.Int("events", 5, "number of events to send")
+	workers := flag.Int("workers", 4, "number of workers shared by all events")
+	flag.Parse()
+
+	pool := workerpool.New(*workers)
+	defer pool.Shutdown()
+
+	n := &Notifier{
+		pool: pool,
+		adapters: []Adapter{
+			fake("email", 300*time.Millisecond, 200*time.Millisecond, 0.1),
+			fake("sms", 100*time.Millisecond, 150*time.Millisecond, 0.2),
+			fake("push", 50*time.Millisecond, 40*time.Millisecond, 0.05),
+		},
+	}
+
+	for i := 1; i <= *events; i++ {
+		e := Event{User: fmt.Sprintf("user-%d", i), Message: "your order has shipped"}
+		err := n.Notify(context.Background(), e)
+		if err == nil {
+			log.Printf("%s: delivered on every channel", e.User)
+			continue
+		}
+		log.Printf("%s: %s", e.User, report(err))
+	}
+}
+
+// report summarises the channels that failed, and whether they timed out.
+func report(err error) string {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	var failed []string
+	for _, err := range errs {
+		var de *DeliveryError
+		switch {
+		case errors.Is(err, context.DeadlineExceeded) && errors.As(err, &de):
+			failed = append(failed, de.Channel+" timed out")
+		case errors.As(err, &de):
+			failed = append(failed, de.Error())
+		default:
+			failed = append(failed, err.Error())
+		}
+	}
+	return "failed on " + strings.Join(failed, "; ")
+}
@@ -0,0 +1,158 @@
+// Command thumbnail is an example HTTP service that makes thumbnails on a
+// workerpool.Pool. Copy it as a starting point for services of your own:
+// the HTTP side only accepts and answers requests, and all the real work
+// happens on the pool, one Future per request.
+//
+// The pool has a bounded queue, and the service sheds load rather than
+// letting requests pile up: a request gets 429 Too Many Requests when the
+// queue is full, or when it has waited in the queue longer than -maxwait.
+// The pool's dashboard, from poolhttp, is under /debug/pool/: its counters,
+// the thumbnails being made and the latest failures.
+//
+//	go run ./examples/thumbnail -addr :8080
+//	curl --data-binary @photo.jpg localhost:8080/thumbnail > thumb.jpg
+//	curl localhost:8080/debug/pool/
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"time"
+
+	"workerpool"
+	"workerpool/poolhttp"
+)
+
+const maxUpload = 10 << 20
+
+var (
+	addr    = flag.String("addr", ":8080", "address to listen on")
+	workers = flag.Int("workers", runtime.NumCPU(), "number of workers")
+	queue   = flag.Int("queue", 64, "requests that may wait for a worker")
+	maxWait = flag.Duration("maxwait", 2*time.Second, "longest a request may wait for a worker")
+	size    = flag.Int("size", 256, "longest side of a thumbnail, in pixels")
+)
+
+func main() {
+	flag.Parse()
+
+	pool := workerpool.New(*workers,
+		workerpool.WithQueueCapacity(*queue, workerpool.Reject),
+		workerpool.WithTracking(100))
+
+	mux := http.NewServeMux()
+	mux.Handle("/thumbnail", thumbnailHandler(pool))
+	mux.Handle("/debug/pool/", http.StripPrefix("/debug/pool", poolhttp.New(pool)))
+	srv := &http.Server{Addr: *addr, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	log.Printf("listening on %s with %d workers", *addr, *workers)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	// The server has answered every request, so nothing is waiting on the
+	// pool any more.
+	pool.Shutdown()
+}
+
+func thumbnailHandler(pool *workerpool.Pool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(http.MaxBytesReader(w, r.Body, maxUpload)); err != nil {
+			code := http.StatusBadRequest
+			if _, ok := err.(*http.MaxBytesError); ok {
+				code = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		// The deadline only applies while the request is queued: the
+		// task doesn't look at its context, so once a worker has started
+		// on it the thumbnail gets finished.
+		queued, cancel := context.WithTimeout(r.Context(), *maxWait)
+		defer cancel()
+		f := workerpool.SubmitFutureContext(queued, pool, func(context.Context) ([]byte, error) {
+			return thumbnail(body.Bytes(), *size)
+		})
+
+		thumb, err := f.Await(r.Context())
+		switch {
+		case errors.Is(err, workerpool.ErrQueueFull), errors.Is(err, context.DeadlineExceeded):
+			w.Header().Set("Retry-After", strconv.Itoa(int(max(*maxWait/time.Second, 1))))
+			http.Error(w, "busy, try again later", http.StatusTooManyRequests)
+		case errors.Is(err, image.ErrFormat):
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		case r.Context().Err() != nil:
+			// The client has gone; there is nobody to answer.
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(thumb)
+		}
+	})
+}
+
+// thumbnail decodes a JPEG or PNG image and returns it as a JPEG no larger
+// than side pixels either way.
+func thumbnail(data []byte, side int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > side || h > side {
+		if w >= h {
+			w, h = side, max(h*side/w, 1)
+		} else {
+			w, h = max(w*side/h, 1), side
+		}
+	}
+
+	// Nearest-neighbour scaling is crude, but it keeps the example free
+	// of dependencies.
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	} else {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				dst.Set(x, y, src.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h))
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+package workerpool
+
+import "context"
+
+// Future is the eventual result of a task started with SubmitFuture.
+type Future[R any] struct {
+	done  chan struct{}
+	value R
+	err   error
+}
+
+func newFuture[R any]() *Future[R] {
+	return &Future[R]{done: make(chan struct{})}
+}
+
+// complete records the outcome and releases everyone waiting. It must be
+// called exactly once.
+func (f *Future[R]) complete(value R, err error) {
+	f.value, f.err = value, err
+	close(f.done)
+}
+
+// Done returns a channel that is closed once the result is available.
+func (f *Future[R]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err returns the task's error once it has finished, and nil before that.
+func (f *Future[R]) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Await blocks until the task has finished and returns its result, or
+// returns ctx's error if ctx is done first. Giving up on a Future does not
+// cancel the task.
+func (f *Future[R]) Await(ctx context.Context) (R, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+// SubmitFuture submits fn to p and returns a Future for its result, so the
+// caller can collect it later without plumbing a result channel of its own.
+// If the task cannot be submitted, or is discarded from the queue before it
+// runs, the Future completes with the reason. The task is retried like any
+// other under WithRetry, and the Future completes after the last attempt.
+// The task's error is reported through the Future only, not by Pool.Wait;
+// a panic is reported to both.
+func SubmitFuture[R any](p *Pool, fn func(ctx context.Context) (R, error)) *Future[R] {
+	return SubmitFutureContext(context.Background(), p, fn)
+}
+
+// SubmitFutureContext is SubmitFuture with the task tied to ctx, as with
+// Pool.SubmitContext.
+func SubmitFutureContext[R any](ctx context.Context, p *Pool, fn func(ctx context.Context) (R, error)) *Future[R] {
+	f := newFuture[R]()
+	var value R
+	if err := p.enqueue(&Job{
+		task: func(ctx context.Context) (err error) {
+			value, err = fn(ctx)
+			return err
+		},
+		ctx: ctx,
+		finish: func(err error) {
+			f.complete(value, err)
+		},
+		silent: true,
+	}); err != nil {
+		f.complete(value, err)
+	}
+	return f
+}
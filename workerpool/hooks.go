@@ -0,0 +1,30 @@
+package workerpool
+
+import "context"
+
+// WorkerHooks are called on a worker's own goroutine as it starts and
+// stops and around every task it runs, for per-worker resources such as a
+// database connection, or tracing. Any of them may be nil. The workerID is
+// the worker's number, as returned by WorkerID and used in its name.
+type WorkerHooks struct {
+	// OnStart is called when the worker starts, before its first task.
+	OnStart func(workerID int)
+
+	// OnStop is called when the worker exits: after its last task,
+	// because the pool shut down, was cancelled or shrank.
+	OnStop func(workerID int)
+
+	// OnTaskStart is called before each run of a task, with the task's
+	// context; the task runs with the context it returns, which must be
+	// derived from ctx.
+	OnTaskStart func(ctx context.Context, workerID int) context.Context
+
+	// OnTaskEnd is called after each run of a task with the context the
+	// task ran with and its error, a *PanicError if it panicked.
+	OnTaskEnd func(ctx context.Context, workerID int, err error)
+}
+
+// WithWorkerHooks sets hooks into the lifecycle of the pool's workers.
+func WithWorkerHooks(h WorkerHooks) Option {
+	return func(c *config) { c.hooks = h }
+}
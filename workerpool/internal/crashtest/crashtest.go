@@ -0,0 +1,179 @@
+// Package crashtest tortures a seglog: it drives a log with a random mix of
+// appends, acks and compactions, crashes it at a random failpoint
+// (including part way through writing a record), reopens it and checks
+// that:
+//
+//   - every entry whose Append returned and whose Ack did not is still
+//     pending, with its original payload;
+//   - no entry whose Ack returned is pending again, except the one whose
+//     Ack was in flight at the crash;
+//   - the only pending entry nobody saw appended is the one whose Append
+//     was in flight;
+//   - Append keeps returning higher IDs than it ever has, across crashes.
+//
+// cmd/seglogcrash runs it at length, and seglog's tests briefly.
+package crashtest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"workerpool/seglog"
+)
+
+var errCrash = errors.New("simulated crash")
+
+// model is what the log's users know to have happened.
+type model struct {
+	pending map[uint64][]byte // appended and not acked
+	lastID  uint64            // the highest ID Append has returned
+
+	// The operation in flight when the log crashed, if any.
+	appending []byte
+	acking    uint64
+}
+
+// Torture crashes and reopens a log in dir, which should be empty, the
+// given number of times, with options and operations drawn from rng. It
+// returns the first violation it finds.
+func Torture(dir string, rng *rand.Rand, crashes int) error {
+	policies := []seglog.SyncPolicy{seglog.SyncAlways, seglog.SyncInterval, seglog.SyncNever}
+	opts := seglog.Options{
+		SegmentSize: int64(64 + rng.Intn(512)),
+		Sync:        policies[rng.Intn(len(policies))],
+		SyncEvery:   time.Millisecond,
+	}
+	m := &model{pending: make(map[uint64][]byte)}
+
+	for c := 0; c < crashes; c++ {
+		// Crash somewhere within the next few hundred failpoints after
+		// Open. Failpoints are consulted with the log's lock held, but
+		// armed is set while its sync loop may be running.
+		countdown := 1 + rng.Intn(300)
+		var armed atomic.Bool
+		opts.Failpoint = func(string) error {
+			if !armed.Load() {
+				return nil
+			}
+			if countdown--; countdown == 0 {
+				return errCrash
+			}
+			return nil
+		}
+
+		l, err := seglog.Open(dir, opts)
+		if err != nil {
+			return fmt.Errorf("open after crash %d: %w", c, err)
+		}
+		armed.Store(true)
+		if err := m.check(l); err != nil {
+			return fmt.Errorf("after crash %d: %w", c, err)
+		}
+		if err := m.drive(rng, l); !errors.Is(err, errCrash) {
+			return fmt.Errorf("unexpected error: %v", err)
+		}
+		l.Close()
+	}
+	return nil
+}
+
+// drive runs random operations until the log crashes.
+func (m *model) drive(rng *rand.Rand, l *seglog.Log) error {
+	for {
+		switch n := rng.Intn(100); {
+		case n < 55:
+			data := make([]byte, 1+rng.Intn(40))
+			rng.Read(data)
+			m.appending = data
+			id, err := l.Append(data)
+			if err != nil {
+				return err
+			}
+			if id <= m.lastID {
+				return fmt.Errorf("Append returned %d after %d", id, m.lastID)
+			}
+			m.lastID = id
+			m.appending = nil
+			m.pending[id] = data
+
+		case n < 95:
+			id, ok := m.any(rng)
+			if !ok {
+				continue
+			}
+			m.acking = id
+			if err := l.Ack(id); err != nil {
+				return err
+			}
+			m.acking = 0
+			delete(m.pending, id)
+
+		default:
+			if err := l.Compact(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// check compares a freshly reopened log against the model, then brings
+// the model up to date with how the in-flight operation turned out.
+func (m *model) check(l *seglog.Log) error {
+	got := make(map[uint64][]byte)
+	if err := l.Replay(func(id uint64, data []byte) error {
+		got[id] = data
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for id, want := range m.pending {
+		data, ok := got[id]
+		if !ok {
+			if id == m.acking {
+				continue // the ack made it to disk
+			}
+			return fmt.Errorf("entry %d was lost", id)
+		}
+		if !bytes.Equal(data, want) {
+			return fmt.Errorf("entry %d has payload %x, want %x", id, data, want)
+		}
+	}
+
+	var strays int
+	for id, data := range got {
+		if _, ok := m.pending[id]; ok {
+			continue
+		}
+		if m.appending == nil || !bytes.Equal(data, m.appending) {
+			return fmt.Errorf("entry %d is pending but was never appended or was acked", id)
+		}
+		if strays++; strays > 1 {
+			return fmt.Errorf("in-flight append came back more than once")
+		}
+		m.lastID = max(m.lastID, id)
+	}
+
+	m.pending = got
+	m.appending = nil
+	m.acking = 0
+	return nil
+}
+
+func (m *model) any(rng *rand.Rand) (uint64, bool) {
+	if len(m.pending) == 0 {
+		return 0, false
+	}
+	i := rng.Intn(len(m.pending))
+	for id := range m.pending {
+		if i == 0 {
+			return id, true
+		}
+		i--
+	}
+	return 0, false
+}
@@ -0,0 +1,49 @@
+package workerpool
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Logger receives what the pool logs: panics not given to a PanicHandler,
+// at slog.LevelError, the outcome of every task run, at slog.LevelDebug,
+// and more as set by SetDebug. A *slog.Logger is a Logger.
+//
+// Records from a worker carry the fields worker_id and worker for it; task
+// runs also have task for its TaskID, duration for how long the run took,
+// and err for its error.
+type Logger interface {
+	Enabled(ctx context.Context, level slog.Level) bool
+	Log(ctx context.Context, level slog.Level, msg string, args ...any)
+}
+
+// WithLogger makes the pool log to l rather than slog.Default(), or
+// nowhere if l is nil.
+func WithLogger(l Logger) Option {
+	if l == nil {
+		l = nopLogger{}
+	}
+	return func(c *config) { c.logger = l }
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Enabled(context.Context, slog.Level) bool        { return false }
+func (nopLogger) Log(context.Context, slog.Level, string, ...any) {}
+
+// log logs msg to the pool's logger, tagged with the worker of ctx if it is
+// a task's, if the logger wants records of the given level.
+func (p *Pool) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l := p.logger
+	if l == nil {
+		// Looked up each time, so that slog.SetDefault takes effect.
+		l = slog.Default()
+	}
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	if w, ok := ctx.Value(workerKey{}).(workerInfo); ok {
+		args = append([]any{"worker_id", w.id, "worker", w.name}, args...)
+	}
+	l.Log(ctx, level, msg, args...)
+}
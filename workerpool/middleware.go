@@ -0,0 +1,30 @@
+package workerpool
+
+// Middleware wraps the running of tasks with behaviour of its own, such as
+// logging, timing or adding values to the task's context:
+//
+//	func timed(next workerpool.Task) workerpool.Task {
+//		return func(ctx context.Context) error {
+//			start := time.Now()
+//			err := next(ctx)
+//			log.Printf("%s: task took %v", workerpool.WorkerName(ctx), time.Since(start))
+//			return err
+//		}
+//	}
+type Middleware func(next Task) Task
+
+// WithMiddleware wraps every run of every task in mw, the first outermost.
+// Middleware runs on the worker, inside the pool's panic recovery, and sees
+// each retry as a run of its own. Using WithMiddleware more than once adds
+// to the chain.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *config) { c.middleware = append(c.middleware, mw...) }
+}
+
+// wrap returns task wrapped in the pool's middleware.
+func (p *Pool) wrap(task Task) Task {
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		task = p.middleware[i](task)
+	}
+	return task
+}
@@ -0,0 +1,55 @@
+package workerpool
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+)
+
+type workerKey struct{}
+
+// workerInfo identifies a worker; task contexts carry their worker's.
+type workerInfo struct {
+	id   int
+	name string
+	pool *Pool
+}
+
+// Name returns the name given with WithName, if any.
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// WorkerName returns the name of the worker running the task whose context
+// is ctx, such as "resize-pool-worker-3", or "" if ctx is not a task's.
+// Workers are numbered from 1 in the order they are started, and numbers
+// are not reused.
+func WorkerName(ctx context.Context) string {
+	w, _ := ctx.Value(workerKey{}).(workerInfo)
+	return w.name
+}
+
+// WorkerID returns the number of the worker running the task whose context
+// is ctx, as used in its name and passed to WorkerHooks, or 0 if ctx is not
+// a task's.
+func WorkerID(ctx context.Context) int {
+	w, _ := ctx.Value(workerKey{}).(workerInfo)
+	return w.id
+}
+
+func (p *Pool) workerName(n int) string {
+	if p.name == "" {
+		return "worker-" + strconv.Itoa(n)
+	}
+	return p.name + "-worker-" + strconv.Itoa(n)
+}
+
+// workerContext returns ctx labelled, for pprof, WorkerName and WorkerID,
+// as the context of worker w.
+func (p *Pool) workerContext(ctx context.Context, w workerInfo) context.Context {
+	labels := pprof.Labels("worker", w.name)
+	if p.name != "" {
+		labels = pprof.Labels("pool", p.name, "worker", w.name)
+	}
+	return context.WithValue(pprof.WithLabels(ctx, labels), workerKey{}, w)
+}
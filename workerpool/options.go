@@ -0,0 +1,127 @@
+package workerpool
+
+import "workerpool/backoff"
+
+// Option configures a Pool.
+type Option func(*config)
+
+type config struct {
+	queue     Queue
+	capacity  int
+	overflow  OverflowPolicy
+	failFast  bool
+	onPanic   PanicHandler
+	onReport  PanicReportHandler
+	autoscale *Autoscale
+	onDead    DeadLetterHandler
+	limit     *limiter
+	name      string
+	hooks     WorkerHooks
+	logger    Logger
+
+	reserved    int
+	reservedFor int
+	slots       int
+
+	middleware []Middleware
+
+	tracking bool
+	history  int
+
+	retryAttempts int
+	retryBackoff  backoff.Policy
+}
+
+func newConfig(opts []Option) config {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.queue == nil {
+		c.queue = NewPriority()
+	}
+	return c
+}
+
+// WithName names the pool. The name shows up in the names of its workers,
+// as in "resize-pool-worker-3", and in their pprof labels.
+func WithName(name string) Option {
+	return func(c *config) { c.name = name }
+}
+
+// WithQueue replaces the default priority queue.
+func WithQueue(q Queue) Option {
+	return func(c *config) { c.queue = q }
+}
+
+// WithQueueCapacity bounds the queue to capacity waiting tasks, with policy
+// deciding what Submit does when it is full. Without it the queue is
+// unbounded.
+func WithQueueCapacity(capacity int, policy OverflowPolicy) Option {
+	if capacity <= 0 {
+		panic("workerpool: queue capacity must be positive")
+	}
+	return func(c *config) {
+		c.capacity = capacity
+		c.overflow = policy
+	}
+}
+
+// WithFailFast makes the first task error cancel the pool, much like
+// errgroup.WithContext: running tasks see their context cancelled, queued
+// tasks are discarded, and Submit returns the error from then on.
+func WithFailFast() Option {
+	return func(c *config) { c.failFast = true }
+}
+
+// WithPanicHandler sets the function told about panicking tasks. The worker
+// recovers and carries on either way, and the task's error is a
+// *PanicError; by default the panic is logged, see WithLogger.
+func WithPanicHandler(h PanicHandler) Option {
+	return func(c *config) { c.onPanic = h }
+}
+
+// WithPanicReports sets the function given a complete *PanicError for
+// each panicking task, including the stacks of all goroutines and the
+// payload snapshot from WithSnapshot, so crash triage has everything in
+// one place. It is called after any PanicHandler, and before the task
+// goes to the dead-letter handler, whose DeadLetter.Err is the same
+// report.
+func WithPanicReports(h PanicReportHandler) Option {
+	return func(c *config) { c.onReport = h }
+}
+
+// WithDeadLetter sets the function given the tasks that fail for good,
+// after any retries, so that they can be inspected or kept rather than
+// just counted. Their errors are still reported by Wait.
+func WithDeadLetter(h DeadLetterHandler) Option {
+	return func(c *config) { c.onDead = h }
+}
+
+// WithRateLimit limits how fast the workers start tasks to rps a second on
+// average, however many workers there are, letting through bursts of up to
+// burst tasks after a quiet spell. Tasks held back wait in the queue, where
+// they can still be cancelled, and count towards its capacity.
+func WithRateLimit(rps float64, burst int) Option {
+	if rps <= 0 || burst < 1 {
+		panic("workerpool: rate limit needs rps > 0 and burst >= 1")
+	}
+	return func(c *config) { c.limit = newLimiter(rps, burst) }
+}
+
+// WithRetry runs a failing task again, up to maxAttempts runs in all, with
+// the delays given by policy in between, instead of giving up on the first
+// error. Only the error of the last attempt is reported. Tasks that panic,
+// or whose context was cancelled, are not retried.
+//
+// A task waiting out its backoff is not in the queue, so it doesn't count
+// against the queue's capacity, but it does keep Wait and Shutdown waiting.
+func WithRetry(maxAttempts int, policy backoff.Policy) Option {
+	if maxAttempts < 1 {
+		panic("workerpool: maxAttempts must be at least 1")
+	}
+	return func(c *config) {
+		c.retryAttempts = maxAttempts
+		c.retryBackoff = policy
+	}
+}
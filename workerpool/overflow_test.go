@@ -0,0 +1,58 @@
+package workerpool_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"workerpool"
+)
+
+func TestDropOldest(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		queue func() workerpool.Queue
+		want  []string
+	}{
+		{"priority", workerpool.NewPriority, []string{"urgent", "low 2", "new"}},
+		{"FIFO", workerpool.NewFIFO, []string{"urgent", "low 2", "new"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := workerpool.New(1,
+				workerpool.WithQueue(tc.queue()),
+				workerpool.WithQueueCapacity(3, workerpool.DropOldest))
+			b := newBlocker()
+			p.Submit(b.task)
+			<-b.started
+
+			var (
+				mu  sync.Mutex
+				ran []string
+			)
+			task := func(name string) workerpool.Task {
+				return func(context.Context) error {
+					mu.Lock()
+					ran = append(ran, name)
+					mu.Unlock()
+					return nil
+				}
+			}
+			p.SubmitWithPriority(task("low 1"), 0)
+			p.SubmitWithPriority(task("urgent"), 5)
+			p.SubmitWithPriority(task("low 2"), 0)
+			if err := p.Submit(task("new")); err != nil {
+				t.Fatalf("Submit to a full queue = %v, want nil", err)
+			}
+			if s := p.Stats(); s.Dropped != 1 || s.Queued != 3 {
+				t.Errorf("Dropped, Queued = %d, %d, want 1, 3", s.Dropped, s.Queued)
+			}
+
+			close(b.release)
+			p.Shutdown()
+			if !slices.Equal(ran, tc.want) {
+				t.Errorf("ran %q, want %q", ran, tc.want)
+			}
+		})
+	}
+}
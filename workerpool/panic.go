@@ -0,0 +1,127 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError is the error recorded for a task that panicked. It doubles
+// as a crash report, with all there is to know about the task and where it
+// ran; see WithPanicReports.
+type PanicError struct {
+	Value  any    // the value passed to panic
+	Stack  []byte // the panicking goroutine's stack
+	Worker string // name of the worker the task ran on
+
+	WorkerID int
+	TaskID   TaskID
+	Priority int       // the priority the task was submitted with
+	Attempt  int       // the run that panicked, counting from 1
+	Queued   time.Time // when the task was queued for that run
+	Payload  any       // the snapshot given by WithSnapshot, if any
+
+	// Goroutines is the stack of every goroutine at the time of the
+	// panic, taken only for pools with WithPanicReports, since it briefly
+	// stops the world.
+	Goroutines []byte
+}
+
+func (e *PanicError) Error() string {
+	if e.Worker == "" {
+		return fmt.Sprintf("workerpool: task panicked: %v", e.Value)
+	}
+	return fmt.Sprintf("workerpool: task panicked on %s: %v", e.Worker, e.Value)
+}
+
+// PanicHandler is called on the worker's goroutine with the task that
+// panicked, the recovered value and the stack at the point of the panic.
+type PanicHandler func(task any, recovered any, stack []byte)
+
+// PanicReportHandler is called on the worker's goroutine with the report
+// on each task that panicked, once the pool has filled it in.
+type PanicReportHandler func(report *PanicError)
+
+type snapshotKey struct{}
+
+// WithSnapshot returns a copy of ctx that has snapshot describe the
+// payload of the task submitted with it, for the PanicError of a panic.
+// snapshot is only called if the task panics, on the worker, so it should
+// copy what it needs rather than rely on the task's state being intact.
+func WithSnapshot(ctx context.Context, snapshot func() any) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, snapshot)
+}
+
+// call runs task, turning a panic into a *PanicError after reporting it to
+// the pool's panic handler, or logging it if it has none, so one bad task
+// doesn't take the worker (and the process) down with it.
+func (p *Pool) call(ctx context.Context, task Task) (err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			pe := &PanicError{
+				Value:    r,
+				Stack:    debug.Stack(),
+				Worker:   WorkerName(ctx),
+				WorkerID: WorkerID(ctx),
+			}
+			if p.onReport != nil {
+				pe.Goroutines = goroutines()
+			}
+			if p.onPanic != nil {
+				p.onPanic(task, r, pe.Stack)
+			} else {
+				p.log(ctx, slog.LevelError, "workerpool: task panicked",
+					"duration", time.Since(start), "err", pe, "stack", string(pe.Stack))
+			}
+			err = pe
+		}
+	}()
+	return task(ctx)
+}
+
+// report fills in what run knows of j in err, if err is the error of a
+// panic, and hands it to the pool's report handler.
+func (p *Pool) report(j *Job, err error) {
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		return
+	}
+	pe.TaskID = j.id
+	pe.Priority = j.priority
+	pe.Attempt = j.attempt
+	pe.Queued = j.queued
+	if snapshot, ok := j.ctx.Value(snapshotKey{}).(func() any); ok {
+		pe.Payload = safeSnapshot(snapshot)
+	}
+	if p.onReport != nil {
+		p.onReport(pe)
+	}
+}
+
+// safeSnapshot calls snapshot, which is likely to look at the state that
+// made the task panic, giving the panic value instead if it panics too.
+func safeSnapshot(snapshot func() any) (v any) {
+	defer func() {
+		if r := recover(); r != nil {
+			v = fmt.Sprintf("snapshot panicked: %v", r)
+		}
+	}()
+	return snapshot()
+}
+
+// goroutines returns the stacks of all goroutines.
+func goroutines() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
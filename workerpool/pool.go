@@ -0,0 +1,677 @@
+// Package workerpool is the worker pool pattern from the playground programs
+// (see workerpool3 and workerpool4) pulled out into something importable: a
+// fixed number of goroutines pulling tasks off a shared queue, with a
+// WaitGroup to know when they have all finished.
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"workerpool/backoff"
+)
+
+// ErrClosed is returned by Submit once the pool has been shut down.
+var ErrClosed = errors.New("workerpool: pool is shut down")
+
+// ErrAborted is the cause of the cancellation of tasks stopped by Abort.
+var ErrAborted = errors.New("workerpool: pool aborted")
+
+// errDrained is the cause Drain releases the pool's context with, once
+// nothing is left to run; there is nothing to discard then.
+var errDrained = errors.New("workerpool: pool drained")
+
+// Task is a unit of work. The context is cancelled when the pool's context
+// is, or when the context the task was submitted with is, so long-running
+// tasks should watch it and return early. A non-nil error is collected and
+// reported by Wait.
+type Task func(ctx context.Context) error
+
+// Pool runs submitted tasks on a fixed number of worker goroutines.
+type Pool struct {
+	wg sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	// exec runs a single task on behalf of a worker. Normally it is just
+	// call; scoped pools forward the call to their parent instead.
+	exec func(ctx context.Context, task Task) error
+
+	// standsIn is the parent of a scope opened on one of the parent's
+	// workers, which runs its tasks itself in place of that worker.
+	standsIn *Pool
+
+	capacity int
+	overflow OverflowPolicy
+	failFast bool
+	onPanic  PanicHandler
+	onReport PanicReportHandler
+	onDead   DeadLetterHandler
+	name     string
+	hooks    WorkerHooks
+	logger   Logger
+
+	middleware []Middleware
+
+	reserved    int // workers kept for jobs of priority reservedFor and up
+	reservedFor int
+	slots       int // total slots for weighted jobs, 0 if unlimited
+
+	retryAttempts int
+	retryBackoff  backoff.Policy
+
+	// debug is the DebugLevel, read without holding mu.
+	debug atomic.Int32
+
+	// mu guards everything below. cond is signalled whenever queue,
+	// retries, closed or size change or the pool's context is cancelled;
+	// room when the queue shrinks or the pool stops; idle when pending
+	// drops to zero.
+	mu      sync.Mutex
+	cond    *sync.Cond
+	room    *sync.Cond
+	idle    *sync.Cond
+	queue   Queue
+	retries map[*Job]*time.Timer       // jobs waiting out their backoff
+	watches map[<-chan struct{}]*watch // by the done channel they watch
+	limit   *limiter                   // nil if dispatch isn't rate limited
+	closed  bool
+	size    int // workers wanted
+	spawned int // workers ever started, for naming them
+	running int // workers alive
+	busy    int // workers running a task
+	busyLow int // of which running a task below reservedFor
+	taken   int // slots taken by running tasks
+	pending int // queued, running or retrying tasks
+	errs    []error
+	failed  bool
+	stats   Stats
+
+	cancelled time.Time // when the pool's context was cancelled
+
+	// With WithTracking, tracked holds the records of the tasks in the
+	// pool and of the last history to finish, listed in finished.
+	lastID   TaskID
+	tracked  map[TaskID]*record
+	finished []TaskID
+	history  int
+}
+
+// New starts numWorkers workers, initially blocked because there are no
+// tasks yet.
+func New(numWorkers int, opts ...Option) *Pool {
+	return NewWithContext(context.Background(), numWorkers, opts...)
+}
+
+// NewWithContext is like New, but the pool stops when ctx is cancelled:
+// workers stop picking up tasks, queued tasks are discarded, the context
+// passed to running tasks is cancelled, and Submit returns ctx's error.
+func NewWithContext(ctx context.Context, numWorkers int, opts ...Option) *Pool {
+	return newPool(ctx, numWorkers, nil, opts)
+}
+
+// newPool starts a pool whose workers run tasks with exec, or with call if
+// exec is nil.
+func newPool(ctx context.Context, numWorkers int, exec func(context.Context, Task) error, opts []Option) *Pool {
+	if numWorkers <= 0 {
+		panic("workerpool: numWorkers must be positive")
+	}
+
+	cfg := newConfig(opts)
+	p := &Pool{
+		exec:     exec,
+		capacity: cfg.capacity,
+		overflow: cfg.overflow,
+		failFast: cfg.failFast,
+		onPanic:  cfg.onPanic,
+		onReport: cfg.onReport,
+		onDead:   cfg.onDead,
+		name:     cfg.name,
+		hooks:    cfg.hooks,
+		logger:   cfg.logger,
+
+		middleware: cfg.middleware,
+
+		reserved:    cfg.reserved,
+		reservedFor: cfg.reservedFor,
+		slots:       cfg.slots,
+		queue:       cfg.queue,
+		limit:       cfg.limit,
+		retries:     make(map[*Job]*time.Timer),
+		watches:     make(map[<-chan struct{}]*watch),
+
+		retryAttempts: cfg.retryAttempts,
+		retryBackoff:  cfg.retryBackoff,
+	}
+	if _, ok := p.queue.(peeker); (p.reserved > 0 || p.slots > 0) && !ok {
+		panic("workerpool: reserved workers and slots need a queue with Peek")
+	}
+	if p.exec == nil {
+		p.exec = p.call
+	}
+	if cfg.tracking {
+		p.tracked = make(map[TaskID]*record)
+		p.history = cfg.history
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.room = sync.NewCond(&p.mu)
+	p.idle = sync.NewCond(&p.mu)
+	p.ctx, p.cancel = context.WithCancelCause(ctx)
+	context.AfterFunc(p.ctx, p.discard)
+
+	if a := cfg.autoscale; a != nil {
+		numWorkers = min(max(numWorkers, a.Min), a.Max)
+		go p.autoscale(*a)
+	}
+
+	p.mu.Lock()
+	p.resizeLocked(numWorkers)
+	p.mu.Unlock()
+	return p
+}
+
+// Resize changes the number of workers while the pool keeps running. New
+// workers start straight away; when shrinking, surplus workers exit as soon
+// as they have finished their current task. It does nothing once the pool
+// has stopped.
+func (p *Pool) Resize(numWorkers int) {
+	if numWorkers <= 0 {
+		panic("workerpool: numWorkers must be positive")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || p.ctx.Err() != nil {
+		return
+	}
+	p.resizeLocked(numWorkers)
+}
+
+func (p *Pool) resizeLocked(numWorkers int) {
+	p.size = numWorkers
+	if p.running < p.size {
+		p.wg.Add(p.size - p.running)
+		for ; p.running < p.size; p.running++ {
+			p.spawned++
+			go p.worker(p.spawned)
+		}
+	}
+	// Wake idle workers so the surplus can notice and exit.
+	p.cond.Broadcast()
+}
+
+// Size returns the number of workers the pool is currently sized for.
+// After shrinking, a few more may still be finishing their last task.
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size
+}
+
+// Submit queues task for the next free worker and returns without waiting
+// for it to run. It returns ErrClosed if the pool has been shut down, the
+// cause of the pool's context being cancelled, or ErrQueueFull if the
+// queue is full under the Reject policy.
+func (p *Pool) Submit(task Task) error {
+	return p.SubmitContext(context.Background(), task)
+}
+
+// SubmitContext is like Submit, but ties the task to ctx: if ctx is done
+// while waiting for room in the queue or while the task is queued, the
+// task is abandoned at once, and once running the task's context carries
+// ctx's values and is cancelled along with ctx.
+func (p *Pool) SubmitContext(ctx context.Context, task Task) error {
+	return p.enqueue(&Job{task: task, ctx: ctx})
+}
+
+// SubmitWithPriority is like Submit, but the task jumps ahead of every
+// queued task with a lower priority. Plain Submit uses priority 0, and
+// tasks of equal priority run in the order they were submitted. This needs
+// a priority-aware queue, which the default queue is.
+func (p *Pool) SubmitWithPriority(task Task, priority int) error {
+	return p.enqueue(&Job{task: task, ctx: context.Background(), priority: priority})
+}
+
+// enqueue queues j, which needs at least task and ctx set. j.finish, if
+// set, is called if the job is accepted but leaves the queue without
+// running, or once it has run for the last time.
+func (p *Pool) enqueue(j *Job) error {
+	if err := j.ctx.Err(); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	evicted, err := p.admitLocked(j)
+	p.mu.Unlock()
+	p.event(j.ctx, "workerpool: task submitted", "task", j.id, "err", err)
+
+	if evicted != nil && evicted.finish != nil {
+		evicted.finish(ErrQueueFull)
+	}
+	return err
+}
+
+// admitLocked queues j if it may be, and returns the job dropped to make
+// room for it, if any, for the caller to finish once p.mu is released.
+// Under DropNewest that is j itself, which counts as accepted. p.mu must
+// be held; it is released while blocking.
+func (p *Pool) admitLocked(j *Job) (evicted *Job, err error) {
+	j.queued = time.Now()
+	evicted, err = p.makeRoomLocked(j.ctx)
+	if err == ErrQueueFull && p.overflow == DropNewest {
+		// Accepted, and dropped in the same breath.
+		p.trackLocked(j)
+		p.endLocked(j, err)
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.queue.Push(j)
+	p.pending++
+	p.stats.Submitted++
+	p.trackLocked(j)
+	p.watchLocked(j)
+	p.cond.Signal()
+	return evicted, nil
+}
+
+// oldestLocked takes the job queued longest out of the queue, or the next
+// one to run if the queue can't tell. p.mu must be held.
+func (p *Pool) oldestLocked() *Job {
+	q, ok := p.queue.(interface{ Oldest() *Job })
+	if !ok {
+		return p.queue.Pop()
+	}
+	j := q.Oldest()
+	if j != nil {
+		p.queue.Remove(j)
+	}
+	return j
+}
+
+// makeRoomLocked checks that a job may be queued, applying the overflow
+// policy if the queue is full. It returns the job DropOldest evicted, if
+// any. p.mu must be held; it is released while blocking.
+func (p *Pool) makeRoomLocked(ctx context.Context) (evicted *Job, err error) {
+	var stop func() bool
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+
+	for {
+		if p.closed {
+			return nil, ErrClosed
+		}
+		if p.ctx.Err() != nil {
+			return nil, context.Cause(p.ctx)
+		}
+		if p.capacity <= 0 || p.queue.Len() < p.capacity {
+			return nil, nil
+		}
+
+		switch p.overflow {
+		case DropNewest:
+			p.stats.Submitted++
+			p.stats.Dropped++
+			return nil, ErrQueueFull
+		case DropOldest:
+			if j := p.oldestLocked(); j != nil {
+				p.unwatchLocked(j)
+				p.droppedLocked(j, ErrQueueFull)
+				return j, nil
+			}
+		case Reject:
+			return nil, ErrQueueFull
+		default:
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if stop == nil {
+				stop = context.AfterFunc(ctx, func() {
+					p.mu.Lock()
+					p.room.Broadcast()
+					p.mu.Unlock()
+				})
+			}
+			p.room.Wait()
+		}
+	}
+}
+
+// discard empties the queue once the pool's context is cancelled and wakes
+// the workers so they can exit.
+func (p *Pool) discard() {
+	p.mu.Lock()
+	if context.Cause(p.ctx) != errDrained {
+		p.cancelled = time.Now()
+	}
+	dropped := p.takeAllLocked(p.clearLocked(), context.Cause(p.ctx))
+	if p.pending == 0 && !p.cancelled.IsZero() {
+		p.stats.CancelToIdle = time.Since(p.cancelled)
+	}
+	p.cond.Broadcast()
+	p.room.Broadcast()
+	p.mu.Unlock()
+
+	for _, j := range dropped {
+		if j.finish != nil {
+			j.finish(context.Cause(p.ctx))
+		}
+	}
+}
+
+// takeAllLocked takes the jobs waiting to be retried as well as queued,
+// the jobs just taken from the queue, counts them all as dropped with err
+// and returns them. p.mu must be held.
+func (p *Pool) takeAllLocked(queued []*Job, err error) []*Job {
+	jobs := queued
+	for j, t := range p.retries {
+		t.Stop()
+		delete(p.retries, j)
+		jobs = append(jobs, j)
+	}
+	for _, w := range p.watches {
+		w.stop()
+		w.jobs = nil
+	}
+	clear(p.watches)
+	for _, j := range jobs {
+		j.watch = nil
+		p.droppedLocked(j, err)
+	}
+	return jobs
+}
+
+// clearLocked empties the queue and returns the jobs it held. p.mu must be
+// held.
+func (p *Pool) clearLocked() []*Job {
+	if q, ok := p.queue.(interface{ Clear() []*Job }); ok {
+		return q.Clear()
+	}
+	return p.popAllLocked()
+}
+
+// popAllLocked empties the queue one job at a time, returning the jobs in
+// the order they would have run. p.mu must be held.
+func (p *Pool) popAllLocked() []*Job {
+	var jobs []*Job
+	for j := p.queue.Pop(); j != nil; j = p.queue.Pop() {
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+// Wait blocks until every task submitted so far has finished or been
+// discarded, then returns the errors the tasks returned since the previous
+// call to Wait, joined with errors.Join.
+//
+// With WithFailFast, errors that only report the resulting cancellation
+// are left out, so Wait returns the failure that started it.
+func (p *Pool) Wait() error {
+	p.mu.Lock()
+	for p.pending > 0 {
+		p.idle.Wait()
+	}
+	errs := p.errs
+	p.errs = nil
+	p.mu.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// attemptLocked records the time one run of j took. p.mu must be held.
+func (p *Pool) attemptLocked(j *Job, wait, run time.Duration) {
+	p.busy--
+	if p.reserved > 0 && !p.urgent(j) {
+		p.busyLow--
+		// A job held back for want of an unreserved worker can go.
+		p.cond.Signal()
+	}
+	if p.slots > 0 {
+		p.taken -= j.Weight()
+		// The slots freed may be enough for several jobs.
+		p.cond.Broadcast()
+	}
+	p.stats.WaitTime += wait
+	p.stats.RunTime += run
+}
+
+// doneLocked records that a task has run for the last time, with the error
+// it returned. p.mu must be held.
+func (p *Pool) doneLocked(j *Job, err error) {
+	p.stats.Completed++
+	if err != nil {
+		p.stats.Failed++
+	}
+	p.endLocked(j, err)
+
+	var pe *PanicError
+	if j.silent && !errors.As(err, &pe) {
+		err = nil
+	}
+	if err != nil && !(p.failed && errors.Is(err, context.Canceled)) {
+		p.errs = append(p.errs, err)
+		if p.failFast && !p.failed {
+			p.failed = true
+			// discard needs the lock, and runs on its own goroutine.
+			p.cancel(err)
+		}
+	}
+	p.settledLocked()
+}
+
+// droppedLocked records that a queued task was discarded, because of err.
+// p.mu must be held.
+func (p *Pool) droppedLocked(j *Job, err error) {
+	p.stats.Dropped++
+	p.endLocked(j, err)
+	p.settledLocked()
+}
+
+func (p *Pool) settledLocked() {
+	p.pending--
+	if p.pending == 0 {
+		p.idle.Broadcast()
+		if !p.cancelled.IsZero() && p.stats.CancelToIdle == 0 {
+			p.stats.CancelToIdle = time.Since(p.cancelled)
+		}
+	}
+}
+
+// Shutdown stops accepting tasks and waits for the workers to finish the
+// queued ones, including any retries. It is safe to call more than once.
+func (p *Pool) Shutdown() {
+	p.Drain(context.Background())
+}
+
+// Drain is Shutdown with a deadline: it stops accepting tasks and waits
+// for the queued ones to finish, or for ctx to be done. In the second case
+// it returns ctx's error and the pool carries on draining; call Abort to
+// stop it and collect what is left.
+func (p *Pool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.room.Broadcast()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel(errDrained)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Abort stops the pool at once: it stops accepting tasks, cancels the
+// running ones with cause ErrAborted and waits for them to return. The
+// tasks that had not started yet, queued or waiting to be retried, are
+// returned so that they aren't lost; those submitted with SubmitFuture
+// aren't, their Futures fail with ErrAborted instead.
+func (p *Pool) Abort() []Task {
+	p.mu.Lock()
+	p.closed = true
+	backlog := p.takeAllLocked(p.popAllLocked(), ErrAborted)
+	p.room.Broadcast()
+	p.mu.Unlock()
+
+	p.cancel(ErrAborted)
+
+	var tasks []Task
+	for _, j := range backlog {
+		if j.finish != nil {
+			j.finish(ErrAborted)
+		} else {
+			tasks = append(tasks, j.task)
+		}
+	}
+	p.wg.Wait()
+	return tasks
+}
+
+func (p *Pool) worker(id int) {
+	defer p.wg.Done()
+
+	ctx := p.workerContext(p.ctx, workerInfo{id: id, name: p.workerName(id), pool: p})
+	pprof.SetGoroutineLabels(ctx)
+
+	if h := p.hooks.OnStart; h != nil {
+		h(id)
+	}
+	if h := p.hooks.OnStop; h != nil {
+		defer h(id)
+	}
+
+	// Pull tasks until the pool is shut down and drained, or cancelled.
+	for {
+		j := p.next()
+		if j == nil {
+			return
+		}
+		p.run(ctx, j)
+		if p.debugging(DebugChecks) {
+			p.check(ctx)
+		}
+	}
+}
+
+// next blocks until there is a job to run, returning nil once the worker
+// should exit.
+func (p *Pool) next() *Job {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		if p.ctx.Err() != nil || p.running > p.size {
+			p.running--
+			return nil
+		}
+		if p.holdBackLocked() || p.outOfSlotsLocked() {
+			p.cond.Wait()
+			continue
+		}
+		if p.queue.Len() > 0 && p.throttledLocked() {
+			p.cond.Wait()
+			continue
+		}
+		if j := p.queue.Pop(); j != nil {
+			p.room.Signal()
+			p.unwatchLocked(j)
+			if j.ctx.Err() == nil {
+				p.busy++
+				if p.reserved > 0 && !p.urgent(j) {
+					p.busyLow++
+				}
+				if p.slots > 0 {
+					p.taken += j.Weight()
+				}
+				p.runningLocked(j)
+				return j
+			}
+			// The job's context was cancelled and its sweep is
+			// already on the way; we just got here first.
+			if p.limit != nil {
+				p.limit.refund()
+			}
+			p.droppedLocked(j, j.ctx.Err())
+			if j.finish != nil {
+				go j.finish(j.ctx.Err())
+			}
+			continue
+		}
+		if p.closed && len(p.retries) == 0 {
+			p.running--
+			return nil
+		}
+		p.cond.Wait()
+	}
+}
+
+// run runs j on the worker whose context, derived from the pool's, is
+// worker.
+func (p *Pool) run(worker context.Context, j *Job) {
+	start := time.Now()
+
+	// Keep the submitter's values and labels, whether or not its context
+	// can be cancelled, but say which worker this is, and stop the task
+	// along with the pool.
+	ctx, cancel := context.WithCancelCause(p.workerContext(j.ctx, worker.Value(workerKey{}).(workerInfo)))
+	defer cancel(nil)
+	stop := context.AfterFunc(p.ctx, func() { cancel(context.Cause(p.ctx)) })
+	defer stop()
+	pprof.SetGoroutineLabels(ctx)
+	defer pprof.SetGoroutineLabels(worker)
+
+	j.attempt++
+	p.event(ctx, "workerpool: task started",
+		"task", j.id, "attempt", j.attempt, "wait", start.Sub(j.queued))
+	if h := p.hooks.OnTaskStart; h != nil {
+		ctx = h(ctx, WorkerID(worker))
+	}
+	err := p.exec(ctx, p.wrap(j.task))
+	if h := p.hooks.OnTaskEnd; h != nil {
+		h(ctx, WorkerID(worker), err)
+	}
+	level := slog.LevelDebug
+	if p.debugging(DebugEvents) {
+		level = slog.LevelInfo
+	}
+	p.log(ctx, level, "workerpool: task ran",
+		"task", j.id, "duration", time.Since(start), "attempt", j.attempt, "err", err)
+	p.report(j, err)
+
+	p.mu.Lock()
+	p.attemptLocked(j, start.Sub(j.queued), time.Since(start))
+	if p.retryableLocked(j, err) {
+		p.retryLocked(j)
+		p.mu.Unlock()
+		p.event(ctx, "workerpool: task to be retried", "task", j.id, "attempt", j.attempt)
+		return
+	}
+	dead := p.deadLocked(j, err)
+	p.doneLocked(j, err)
+	p.mu.Unlock()
+
+	if dead {
+		p.onDead(DeadLetter{Task: j.task, Priority: j.priority, Attempts: j.attempt, Err: err})
+	}
+	if j.finish != nil {
+		j.finish(err)
+	}
+}
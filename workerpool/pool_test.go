@@ -0,0 +1,86 @@
+package workerpool_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"workerpool"
+)
+
+type key struct{}
+
+func TestSubmitContextValues(t *testing.T) {
+	cancellable, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "v"))
+	defer cancel()
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"cancellable", cancellable},
+		{"WithValue", context.WithValue(context.Background(), key{}, "v")},
+		{"WithoutCancel", context.WithoutCancel(cancellable)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := workerpool.New(1)
+			defer p.Shutdown()
+
+			got := make(chan any, 1)
+			if err := p.SubmitContext(tc.ctx, func(ctx context.Context) error {
+				got <- ctx.Value(key{})
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if v := <-got; v != "v" {
+				t.Errorf("task saw value %v, want v", v)
+			}
+		})
+	}
+}
+
+func TestCancelToIdle(t *testing.T) {
+	const linger = 20 * time.Millisecond
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := workerpool.NewWithContext(ctx, 1)
+		started := make(chan struct{})
+		p.Submit(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			time.Sleep(linger) // slow to notice
+			return nil
+		})
+		for range 3 {
+			p.Submit(func(context.Context) error { return nil })
+		}
+		<-started
+		cancel()
+		p.Wait()
+
+		s := p.Stats()
+		if s.CancelToIdle < linger || s.CancelToIdle > time.Second {
+			t.Errorf("CancelToIdle = %v, want about %v", s.CancelToIdle, linger)
+		}
+		if s.Dropped != 3 {
+			t.Errorf("Dropped = %d, want 3", s.Dropped)
+		}
+	})
+
+	t.Run("Shutdown", func(t *testing.T) {
+		p := workerpool.New(1)
+		for range 3 {
+			p.Submit(func(context.Context) error {
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+		}
+		p.Shutdown()
+		time.Sleep(10 * time.Millisecond) // for the pool's context to be released
+		if s := p.Stats(); s.CancelToIdle != 0 {
+			t.Errorf("CancelToIdle = %v after Shutdown, want 0", s.CancelToIdle)
+		}
+	})
+}
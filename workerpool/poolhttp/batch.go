@@ -0,0 +1,84 @@
+package poolhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"workerpool"
+)
+
+// maxBatch caps the size of a POST /jobs:batch body.
+const maxBatch = 32 << 20
+
+// Decoder turns one job posted to POST /jobs:batch into the task to run.
+// An error rejects the job with 400 Bad Request.
+type Decoder func(job json.RawMessage) (workerpool.Task, error)
+
+// Option configures a Handler.
+type Option func(*Handler)
+
+// WithJobs enables POST /jobs:batch, with decode turning its jobs into
+// tasks.
+func WithJobs(decode Decoder) Option {
+	return func(h *Handler) { h.decode = decode }
+}
+
+// accepted is the outcome of one job of a batch: Status is 202 Accepted,
+// with the task's ID, or the reason it was rejected, with its error.
+type accepted struct {
+	Status int
+	ID     workerpool.TaskID `json:",omitempty"`
+	Err    string            `json:",omitempty"`
+}
+
+// batch serves POST /jobs:batch. The body is a JSON array of jobs, and the
+// answer, 207 Multi-Status, an array with the outcome of each in the same
+// order. The jobs are submitted together with Pool.SubmitAll, tied to the
+// request's context values but not its cancellation; under the Block
+// overflow policy a full queue holds up the answer.
+func (h *Handler) batch(w http.ResponseWriter, r *http.Request) {
+	var jobs []json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatch)).Decode(&jobs); err != nil {
+		http.Error(w, "bad batch: want a JSON array of jobs", http.StatusBadRequest)
+		return
+	}
+
+	out := make([]accepted, len(jobs))
+	var (
+		tasks []workerpool.Task
+		index []int // of each task in jobs
+	)
+	for i, job := range jobs {
+		task, err := h.decode(job)
+		if err != nil {
+			out[i] = accepted{Status: http.StatusBadRequest, Err: err.Error()}
+			continue
+		}
+		tasks = append(tasks, task)
+		index = append(index, i)
+	}
+
+	ids, errs := h.pool.SubmitAll(context.WithoutCancel(r.Context()), tasks)
+	for k, i := range index {
+		if err := errs[k]; err != nil {
+			out[i] = accepted{Status: rejected(err), Err: err.Error()}
+		} else {
+			out[i] = accepted{Status: http.StatusAccepted, ID: ids[k]}
+		}
+	}
+	writeJSON(w, http.StatusMultiStatus, out)
+}
+
+// rejected returns the status code for a job the pool would not take.
+func rejected(err error) int {
+	switch {
+	case errors.Is(err, workerpool.ErrQueueFull):
+		return http.StatusTooManyRequests
+	case errors.Is(err, workerpool.ErrClosed), errors.Is(err, context.Canceled):
+		// Shut down, or stopped by its context.
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
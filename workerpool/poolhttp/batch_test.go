@@ -0,0 +1,122 @@
+package poolhttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"workerpool"
+	"workerpool/poolhttp"
+)
+
+type result struct {
+	Status int
+	ID     workerpool.TaskID
+	Err    string
+}
+
+// decodeN accepts jobs of the form {"n": 1}, running them with run.
+func decodeN(run func(ctx context.Context, n int) error) poolhttp.Decoder {
+	return func(job json.RawMessage) (workerpool.Task, error) {
+		var j struct{ N int }
+		if err := json.Unmarshal(job, &j); err != nil {
+			return nil, err
+		}
+		if j.N == 0 {
+			return nil, errors.New("n is required")
+		}
+		return func(ctx context.Context) error { return run(ctx, j.N) }, nil
+	}
+}
+
+func postBatch(t *testing.T, h http.Handler, body string) (*httptest.ResponseRecorder, []result) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs:batch", strings.NewReader(body)))
+	var out []result
+	if rec.Code == http.StatusMultiStatus {
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decoding %s: %v", rec.Body, err)
+		}
+	}
+	return rec, out
+}
+
+func TestBatch(t *testing.T) {
+	p := workerpool.New(1, workerpool.WithTracking(10), workerpool.WithQueueCapacity(1, workerpool.Reject))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit(func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer p.Shutdown()
+	defer close(release)
+
+	h := poolhttp.New(p, poolhttp.WithJobs(decodeN(func(context.Context, int) error { return nil })))
+
+	// The worker is busy and the queue holds one job: the first good job
+	// is queued, the bad ones rejected and the last good one finds the
+	// queue full.
+	rec, out := postBatch(t, h, `[{"n": 1}, "not a job", {}, {"n": 2}]`)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status %d, want 207: %s", rec.Code, rec.Body)
+	}
+	want := []int{http.StatusAccepted, http.StatusBadRequest, http.StatusBadRequest, http.StatusTooManyRequests}
+	if len(out) != len(want) {
+		t.Fatalf("%d results, want %d: %+v", len(out), len(want), out)
+	}
+	for i, r := range out {
+		if r.Status != want[i] {
+			t.Errorf("job %d: status %d, want %d (%+v)", i, r.Status, want[i], r)
+		}
+	}
+	if out[0].ID == 0 {
+		t.Error("accepted job has no ID")
+	}
+	if _, ok := p.Status(out[0].ID); !ok {
+		t.Errorf("accepted job %d is unknown to the pool", out[0].ID)
+	}
+	if out[1].Err == "" || out[2].Err != "n is required" || out[3].Err == "" {
+		t.Errorf("rejections lack their errors: %+v", out)
+	}
+
+	if rec, _ := postBatch(t, h, `{"n": 1}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("non-array body: status %d, want 400", rec.Code)
+	}
+}
+
+type key struct{}
+
+func TestBatchRequestValues(t *testing.T) {
+	p := workerpool.New(1, workerpool.WithTracking(10))
+	defer p.Shutdown()
+
+	got := make(chan any, 1)
+	h := poolhttp.New(p, poolhttp.WithJobs(decodeN(func(ctx context.Context, _ int) error {
+		got <- ctx.Value(key{})
+		return nil
+	})))
+	withValue := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key{}, "v")))
+	})
+
+	if rec, _ := postBatch(t, withValue, `[{"n": 1}]`); rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status %d, want 207", rec.Code)
+	}
+	select {
+	case v := <-got:
+		if v != "v" {
+			t.Errorf("task saw request value %v, want v", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task never ran")
+	}
+}
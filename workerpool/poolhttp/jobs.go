@@ -0,0 +1,99 @@
+package poolhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workerpool"
+)
+
+// maxWait caps how long GET /jobs/{id} holds a request.
+const maxWait = 5 * time.Minute
+
+// job serves GET /jobs/{id}: the job's status, at once or, with
+// ?wait=30s, once the job has succeeded or failed or the wait is over,
+// whichever comes first.
+func (h *Handler) job(w http.ResponseWriter, r *http.Request) {
+	id, ok := jobID(w, r)
+	if !ok {
+		return
+	}
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "bad wait: want a duration such as 30s", http.StatusBadRequest)
+			return
+		}
+		wait = min(d, maxWait)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		s, changed, ok := h.pool.StatusChanged(id)
+		if !ok {
+			http.Error(w, "no such job", http.StatusNotFound)
+			return
+		}
+		if changed == nil || wait == 0 {
+			writeJSON(w, http.StatusOK, newTask(s))
+			return
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			wait = 0 // answer with the status as it stands
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// jobEvents serves GET /jobs/{id}/events: a stream of server-sent events,
+// one "status" event for the job's status now and one for each change,
+// ending once the job has succeeded or failed.
+func (h *Handler) jobEvents(w http.ResponseWriter, r *http.Request) {
+	id, ok := jobID(w, r)
+	if !ok {
+		return
+	}
+	s, changed, ok := h.pool.StatusChanged(id)
+	if !ok {
+		http.Error(w, "no such job", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	rc := http.NewResponseController(w)
+	for {
+		data, _ := json.Marshal(newTask(s))
+		fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+		if err := rc.Flush(); err != nil || changed == nil {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+		if s, changed, ok = h.pool.StatusChanged(id); !ok {
+			return // forgotten already
+		}
+	}
+}
+
+// jobID parses the {id} of the request's path, answering 404 if it is not
+// a task ID.
+func jobID(w http.ResponseWriter, r *http.Request) (workerpool.TaskID, bool) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "no such job", http.StatusNotFound)
+		return 0, false
+	}
+	return workerpool.TaskID(id), true
+}
@@ -0,0 +1,157 @@
+package poolhttp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"workerpool"
+	"workerpool/poolhttp"
+)
+
+type status struct {
+	ID    workerpool.TaskID
+	State string
+}
+
+// blocked returns a tracking pool whose one worker runs a task until
+// release is closed, and that task's ID.
+func blocked(t *testing.T) (p *workerpool.Pool, id workerpool.TaskID, release chan struct{}) {
+	t.Helper()
+	p = workerpool.New(1, workerpool.WithTracking(10))
+	release = make(chan struct{})
+	started := make(chan struct{})
+	id, _ = p.SubmitTracked(context.Background(), func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	return p, id, release
+}
+
+func getJob(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func decodeStatus(t *testing.T, rec *httptest.ResponseRecorder) status {
+	t.Helper()
+	var s status
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	return s
+}
+
+func TestJobWait(t *testing.T) {
+	p, id, release := blocked(t)
+	defer p.Shutdown()
+	h := poolhttp.New(p)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- getJob(h, fmt.Sprintf("/jobs/%d?wait=1m", id)) }()
+	select {
+	case <-done:
+		t.Fatal("answered before the job finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case rec := <-done:
+		if s := decodeStatus(t, rec); rec.Code != http.StatusOK || s.ID != id || s.State != "succeeded" {
+			t.Errorf("got %d %+v, want 200 and job %d succeeded", rec.Code, s, id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no answer once the job finished")
+	}
+}
+
+func TestJobWaitTimeout(t *testing.T) {
+	p, id, release := blocked(t)
+	defer p.Shutdown()
+	defer close(release)
+	h := poolhttp.New(p)
+
+	start := time.Now()
+	rec := getJob(h, fmt.Sprintf("/jobs/%d?wait=20ms", id))
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("answered after %v, before the wait was over", d)
+	}
+	if s := decodeStatus(t, rec); rec.Code != http.StatusOK || s.State != "running" {
+		t.Errorf("got %d %+v, want 200 and the job still running", rec.Code, s)
+	}
+}
+
+func TestJobBadRequest(t *testing.T) {
+	p, id, release := blocked(t)
+	defer p.Shutdown()
+	defer close(release)
+	h := poolhttp.New(p)
+
+	for _, tt := range []struct {
+		path string
+		code int
+	}{
+		{fmt.Sprintf("/jobs/%d?wait=soon", id), http.StatusBadRequest},
+		{fmt.Sprintf("/jobs/%d?wait=-1s", id), http.StatusBadRequest},
+		{fmt.Sprintf("/jobs/%d", id+1), http.StatusNotFound},
+		{"/jobs/first", http.StatusNotFound},
+		{fmt.Sprintf("/jobs/%d/events", id+1), http.StatusNotFound},
+	} {
+		if rec := getJob(h, tt.path); rec.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.code)
+		}
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	p, id, release := blocked(t)
+	defer p.Shutdown()
+	srv := httptest.NewServer(poolhttp.New(p))
+	defer srv.Close()
+
+	resp, err := http.Get(fmt.Sprintf("%s/jobs/%d/events", srv.URL, id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	// Read events until the stream ends, releasing the job after the
+	// first.
+	var states []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var s status
+		if err := json.Unmarshal([]byte(data), &s); err != nil {
+			t.Fatalf("decoding %s: %v", data, err)
+		}
+		if states = append(states, s.State); len(states) == 1 {
+			close(release)
+		}
+	}
+	if len(states) == 0 {
+		close(release)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(states) < 2 || states[0] != "running" || states[len(states)-1] != "succeeded" {
+		t.Errorf("events had states %v, want running first and succeeded last", states)
+	}
+}
@@ -0,0 +1,176 @@
+// Package poolhttp serves a pool's state over HTTP, for looking into a
+// running service:
+//
+//	mux.Handle("/debug/pool/", http.StripPrefix("/debug/pool", poolhttp.New(pool)))
+//
+// GET / answers with the pool's Stats, the tasks running and the latest
+// failures, as JSON or, for a browser, as a page that refreshes itself.
+// GET /jobs/{id} answers with the status of one task, by its TaskID;
+// with ?wait=30s it holds on until the task has finished, for up to that
+// long, and GET /jobs/{id}/events streams every change of status as
+// server-sent events instead. With WithJobs, POST /jobs:batch submits a
+// batch of jobs at once and answers with the outcome of each.
+//
+// Tasks are only known for a pool with workerpool.WithTracking, and
+// finished ones only as far back as its history goes.
+package poolhttp
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"workerpool"
+)
+
+// recentFailures is how many failed tasks the overview lists at most.
+const recentFailures = 20
+
+// Handler is the http.Handler for one pool.
+type Handler struct {
+	pool   *workerpool.Pool
+	mux    *http.ServeMux
+	decode Decoder
+}
+
+// New returns a Handler for p.
+func New(p *workerpool.Pool, opts ...Option) *Handler {
+	h := &Handler{pool: p, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mux.HandleFunc("GET /{$}", h.overview)
+	h.mux.HandleFunc("GET /jobs/{id}", h.job)
+	h.mux.HandleFunc("GET /jobs/{id}/events", h.jobEvents)
+	if h.decode != nil {
+		h.mux.HandleFunc("POST /jobs:batch", h.batch)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// overview is the state of the pool as served by GET /.
+type overview struct {
+	Name     string `json:",omitempty"`
+	Stats    workerpool.Stats
+	Running  []task
+	Failures []task // latest first
+}
+
+// task is a workerpool.Status fit for JSON.
+type task struct {
+	ID        workerpool.TaskID
+	State     string
+	Attempts  int
+	Err       string `json:",omitempty"`
+	Submitted time.Time
+	Started   *time.Time `json:",omitempty"`
+	Finished  *time.Time `json:",omitempty"`
+}
+
+func newTask(s workerpool.Status) task {
+	t := task{
+		ID:        s.ID,
+		State:     s.State.String(),
+		Attempts:  s.Attempts,
+		Submitted: s.Submitted,
+	}
+	if s.Err != nil {
+		t.Err = s.Err.Error()
+	}
+	if !s.Started.IsZero() {
+		t.Started = &s.Started
+	}
+	if !s.Finished.IsZero() {
+		t.Finished = &s.Finished
+	}
+	return t
+}
+
+func (h *Handler) overview(w http.ResponseWriter, r *http.Request) {
+	o := overview{
+		Name:     h.pool.Name(),
+		Stats:    h.pool.Stats(),
+		Running:  []task{},
+		Failures: []task{},
+	}
+	for _, s := range h.pool.Tasks() {
+		switch s.State {
+		case workerpool.Running:
+			o.Running = append(o.Running, newTask(s))
+		case workerpool.Failed:
+			o.Failures = append(o.Failures, newTask(s))
+		}
+	}
+	// Tasks come in submission order, and the latest to fail may have
+	// been submitted long before.
+	slices.SortStableFunc(o.Failures, func(a, b task) int { return b.Finished.Compare(*a.Finished) })
+	o.Failures = o.Failures[:min(len(o.Failures), recentFailures)]
+
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		page.Execute(w, o)
+		return
+	}
+	writeJSON(w, http.StatusOK, o)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+var page = template.Must(template.New("overview").Funcs(template.FuncMap{
+	"ago": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return time.Since(*t).Round(time.Millisecond).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="2">
+<title>{{with .Name}}{{.}}{{else}}workerpool{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.2em 1em 0.2em 0; }
+td.err { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>{{with .Name}}{{.}}{{else}}workerpool{{end}}</h1>
+{{with .Stats}}
+<table>
+<tr><th>Workers</th><td>{{.Busy}} busy of {{.Workers}}</td></tr>
+<tr><th>Queued</th><td>{{.Queued}}</td></tr>
+<tr><th>Submitted</th><td>{{.Submitted}}</td></tr>
+<tr><th>Completed</th><td>{{.Completed}}, {{.Failed}} failed</td></tr>
+<tr><th>Dropped</th><td>{{.Dropped}}</td></tr>
+<tr><th>Retried</th><td>{{.Retried}}</td></tr>
+</table>
+{{end}}
+<h2>Running</h2>
+<table>
+<tr><th>ID</th><th>Attempt</th><th>Running for</th></tr>
+{{range .Running}}<tr><td>{{.ID}}</td><td>{{.Attempts}}</td><td>{{ago .Started}}</td></tr>
+{{else}}<tr><td colspan="3">none</td></tr>
+{{end}}</table>
+<h2>Recent failures</h2>
+<table>
+<tr><th>ID</th><th>Attempts</th><th>Failed</th><th>Error</th></tr>
+{{range .Failures}}<tr><td>{{.ID}}</td><td>{{.Attempts}}</td><td>{{ago .Finished}} ago</td><td class="err">{{.Err}}</td></tr>
+{{else}}<tr><td colspan="4">none</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
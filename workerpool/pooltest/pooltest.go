@@ -0,0 +1,162 @@
+// Package pooltest helps unit test code built on workerpool: running a task
+// the way a worker would, but under the test's control, and recording what
+// producers submit instead of running it.
+package pooltest
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"testing"
+	"time"
+
+	"workerpool"
+)
+
+// Outcome is what running a task once produced.
+type Outcome struct {
+	Err      error // the returned error, or a *workerpool.PanicError
+	Panicked bool
+	Duration time.Duration
+}
+
+// Invoke runs task on the calling goroutine with ctx, recovering a panic
+// into a *workerpool.PanicError just as a pool worker would.
+func Invoke(ctx context.Context, task workerpool.Task) (o Outcome) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			o.Err = &workerpool.PanicError{Value: r, Stack: debug.Stack()}
+			o.Panicked = true
+		}
+		o.Duration = time.Since(start)
+	}()
+	o.Err = task(ctx)
+	return o
+}
+
+// Redeliver runs task n times in a row, as happens when a job is retried or
+// redelivered, and returns each outcome. It is meant for checking that a
+// task is idempotent, or that it succeeds after failing attempts.
+func Redeliver(ctx context.Context, task workerpool.Task, n int) []Outcome {
+	outcomes := make([]Outcome, n)
+	for i := range outcomes {
+		outcomes[i] = Invoke(ctx, task)
+	}
+	return outcomes
+}
+
+// Context is a context the test cancels explicitly, for checking how a task
+// behaves when its pool or submitter gives up part way through.
+type Context struct {
+	context.Context
+	cancel context.CancelCauseFunc
+}
+
+// NewContext returns a Context derived from parent. It is cancelled when the
+// test ends, if not before.
+func NewContext(t testing.TB, parent context.Context) *Context {
+	ctx, cancel := context.WithCancelCause(parent)
+	t.Cleanup(func() { cancel(nil) })
+	return &Context{Context: ctx, cancel: cancel}
+}
+
+// Cancel cancels the context.
+func (c *Context) Cancel() { c.cancel(nil) }
+
+// CancelCause cancels the context with the given cause.
+func (c *Context) CancelCause(err error) { c.cancel(err) }
+
+// After cancels the context d from now, typically while the task under
+// test is running.
+func (c *Context) After(d time.Duration) {
+	time.AfterFunc(d, c.Cancel)
+}
+
+// Submission is a task captured by a Recorder.
+type Submission struct {
+	Task workerpool.Task
+	Ctx  context.Context
+	At   time.Time
+}
+
+// Recorder is a workerpool.Submitter that captures submissions instead of
+// running them. Err, if set, is returned by every Submit without recording
+// anything. It is safe for concurrent use.
+type Recorder struct {
+	Err error
+
+	mu          sync.Mutex
+	submissions []Submission
+}
+
+var _ workerpool.Submitter = (*Recorder)(nil)
+
+// Submit records task.
+func (r *Recorder) Submit(task workerpool.Task) error {
+	return r.SubmitContext(context.Background(), task)
+}
+
+// SubmitContext records task along with ctx.
+func (r *Recorder) SubmitContext(ctx context.Context, task workerpool.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	r.submissions = append(r.submissions, Submission{Task: task, Ctx: ctx, At: time.Now()})
+	return nil
+}
+
+// Submissions returns everything recorded so far.
+func (r *Recorder) Submissions() []Submission {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Submission(nil), r.submissions...)
+}
+
+// Len returns the number of recorded submissions.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.submissions)
+}
+
+// RunAll takes the recorded submissions and runs them one at a time with
+// Invoke, in the order they were submitted, until none are left. Tasks that
+// submit more work to the recorder have that run too. Each task gets its
+// submission context.
+func (r *Recorder) RunAll() []Outcome {
+	var outcomes []Outcome
+	for {
+		r.mu.Lock()
+		batch := r.submissions
+		r.submissions = nil
+		r.mu.Unlock()
+
+		if len(batch) == 0 {
+			return outcomes
+		}
+		for _, s := range batch {
+			outcomes = append(outcomes, Invoke(s.Ctx, s.Task))
+		}
+	}
+}
+
+// AssertStats fails the test if the counters in got differ from want.
+// Durations and the instantaneous fields are ignored, since tests cannot
+// predict them.
+func AssertStats(t testing.TB, got, want workerpool.Stats) {
+	t.Helper()
+
+	check := func(name string, got, want uint64) {
+		if got != want {
+			t.Errorf("Stats.%s = %d, want %d", name, got, want)
+		}
+	}
+	check("Submitted", got.Submitted, want.Submitted)
+	check("Completed", got.Completed, want.Completed)
+	check("Failed", got.Failed, want.Failed)
+	check("Dropped", got.Dropped, want.Dropped)
+}
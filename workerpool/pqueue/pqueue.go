@@ -0,0 +1,186 @@
+// Package pqueue provides a bounded priority queue that is safe for
+// concurrent use. Higher priorities come out first; items with equal
+// priority come out in the order they were pushed. Push, Pop and Remove are
+// O(log n).
+package pqueue
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+)
+
+// ErrFull is returned by Push when the queue is at capacity.
+var ErrFull = errors.New("pqueue: queue is full")
+
+// Item is a handle to a pushed value, used to remove it again.
+type Item[T any] struct {
+	value    T
+	priority int
+	seq      uint64
+	index    int // position in the heap, -1 once removed
+}
+
+// Value returns the pushed value.
+func (it *Item[T]) Value() T { return it.value }
+
+// Priority returns the priority the value was pushed with.
+func (it *Item[T]) Priority() int { return it.priority }
+
+// Queue is a priority queue holding at most a fixed number of items.
+type Queue[T any] struct {
+	mu       sync.Mutex
+	items    items[T]
+	capacity int
+	seq      uint64
+	counts   map[int]int
+}
+
+// New returns a queue holding at most capacity items. A capacity of zero or
+// less means the queue is unbounded.
+func New[T any](capacity int) *Queue[T] {
+	return &Queue[T]{capacity: capacity, counts: make(map[int]int)}
+}
+
+// Push adds v with the given priority, returning ErrFull if the queue is at
+// capacity.
+func (q *Queue[T]) Push(v T, priority int) (*Item[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.capacity > 0 && len(q.items) >= q.capacity {
+		return nil, ErrFull
+	}
+	q.seq++
+	it := &Item[T]{value: v, priority: priority, seq: q.seq}
+	heap.Push(&q.items, it)
+	q.counts[priority]++
+	return it, nil
+}
+
+// Pop removes and returns the highest priority item, or reports false if
+// the queue is empty.
+func (q *Queue[T]) Pop() (v T, priority int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return v, 0, false
+	}
+	it := heap.Pop(&q.items).(*Item[T])
+	q.uncount(it.priority)
+	return it.value, it.priority, true
+}
+
+// Peek returns the highest priority item without removing it, or reports
+// false if the queue is empty.
+func (q *Queue[T]) Peek() (v T, priority int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return v, 0, false
+	}
+	it := q.items[0]
+	return it.value, it.priority, true
+}
+
+// Remove takes it out of the queue, reporting whether it was still there.
+func (q *Queue[T]) Remove(it *Item[T]) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if it.index < 0 || it.index >= len(q.items) || q.items[it.index] != it {
+		return false
+	}
+	heap.Remove(&q.items, it.index)
+	q.uncount(it.priority)
+	return true
+}
+
+// Clear removes every item at once, in O(n), and returns their values in
+// no particular order.
+func (q *Queue[T]) Clear() []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	vs := make([]T, len(q.items))
+	for i, it := range q.items {
+		vs[i] = it.value
+		it.index = -1
+	}
+	q.items = nil
+	clear(q.counts)
+	return vs
+}
+
+// Len returns the number of queued items.
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// Cap returns the capacity the queue was created with.
+func (q *Queue[T]) Cap() int {
+	return q.capacity
+}
+
+// Count returns the number of queued items with the given priority.
+func (q *Queue[T]) Count(priority int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.counts[priority]
+}
+
+// Counts returns the number of queued items per priority.
+func (q *Queue[T]) Counts() map[int]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	counts := make(map[int]int, len(q.counts))
+	for p, n := range q.counts {
+		counts[p] = n
+	}
+	return counts
+}
+
+func (q *Queue[T]) uncount(priority int) {
+	if q.counts[priority]--; q.counts[priority] == 0 {
+		delete(q.counts, priority)
+	}
+}
+
+// items implements heap.Interface.
+type items[T any] []*Item[T]
+
+func (h items[T]) Len() int { return len(h) }
+
+func (h items[T]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h items[T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *items[T]) Push(x any) {
+	it := x.(*Item[T])
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *items[T]) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*h = old[:n-1]
+	return it
+}
@@ -0,0 +1,152 @@
+package pqueue_test
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"workerpool/pqueue"
+)
+
+func TestBound(t *testing.T) {
+	q := pqueue.New[int](2)
+	for i := range 2 {
+		if _, err := q.Push(i, 0); err != nil {
+			t.Fatalf("Push %d: %v", i, err)
+		}
+	}
+	if _, err := q.Push(2, 0); !errors.Is(err, pqueue.ErrFull) {
+		t.Fatalf("Push onto a full queue = %v, want ErrFull", err)
+	}
+	q.Pop()
+	if _, err := q.Push(2, 0); err != nil {
+		t.Errorf("Push after Pop freed a place: %v", err)
+	}
+	if q.Len() != 2 || q.Cap() != 2 {
+		t.Errorf("Len, Cap = %d, %d, want 2, 2", q.Len(), q.Cap())
+	}
+
+	u := pqueue.New[int](0)
+	for i := range 1000 {
+		if _, err := u.Push(i, 0); err != nil {
+			t.Fatalf("Push %d onto an unbounded queue: %v", i, err)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	q := pqueue.New[string](0)
+	for _, p := range []struct {
+		v        string
+		priority int
+	}{
+		{"low 1", 0}, {"high 1", 5}, {"low 2", 0}, {"mid", 2}, {"high 2", 5}, {"low 3", 0},
+	} {
+		q.Push(p.v, p.priority)
+	}
+	if v, _, _ := q.Peek(); v != "high 1" {
+		t.Errorf("Peek = %q, want high 1", v)
+	}
+	want := []string{"high 1", "high 2", "mid", "low 1", "low 2", "low 3"}
+	for _, w := range want {
+		if v, _, ok := q.Pop(); !ok || v != w {
+			t.Fatalf("Pop = %q, %v, want %q", v, ok, w)
+		}
+	}
+	if _, _, ok := q.Pop(); ok {
+		t.Error("Pop on an empty queue reported an item")
+	}
+}
+
+func TestCounts(t *testing.T) {
+	q := pqueue.New[int](0)
+	var items []*pqueue.Item[int]
+	for i := range 6 {
+		it, _ := q.Push(i, i%3)
+		items = append(items, it)
+	}
+	if want := map[int]int{0: 2, 1: 2, 2: 2}; !maps.Equal(q.Counts(), want) {
+		t.Errorf("Counts = %v, want %v", q.Counts(), want)
+	}
+	q.Pop() // a priority 2 item
+	q.Remove(items[5])
+	if n := q.Count(2); n != 0 {
+		t.Errorf("Count(2) = %d after taking both out, want 0", n)
+	}
+	if want := map[int]int{0: 2, 1: 2}; !maps.Equal(q.Counts(), want) {
+		t.Errorf("Counts = %v, want %v", q.Counts(), want)
+	}
+	q.Clear()
+	if len(q.Counts()) != 0 || q.Len() != 0 {
+		t.Errorf("after Clear, Counts = %v, Len = %d", q.Counts(), q.Len())
+	}
+}
+
+func TestRemove(t *testing.T) {
+	q := pqueue.New[int](0)
+	var items []*pqueue.Item[int]
+	for i := range 5 {
+		it, _ := q.Push(i, 0)
+		items = append(items, it)
+	}
+	if !q.Remove(items[2]) {
+		t.Fatal("Remove of a queued item reported false")
+	}
+	if q.Remove(items[2]) {
+		t.Error("second Remove reported true")
+	}
+	for _, w := range []int{0, 1, 3, 4} {
+		if v, _, _ := q.Pop(); v != w {
+			t.Fatalf("Pop = %d, want %d", v, w)
+		}
+	}
+	if q.Remove(items[0]) {
+		t.Error("Remove of a popped item reported true")
+	}
+
+	it, _ := q.Push(9, 0)
+	q.Clear()
+	if q.Remove(it) {
+		t.Error("Remove of a cleared item reported true")
+	}
+}
+
+func BenchmarkPushPop(b *testing.B) {
+	q := pqueue.New[int](0)
+	for i := range 1000 {
+		q.Push(i, i%10)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Push(i, i%10)
+		q.Pop()
+	}
+}
+
+func BenchmarkPushPopParallel(b *testing.B) {
+	q := pqueue.New[int](0)
+	for i := range 1000 {
+		q.Push(i, i%10)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			q.Push(i, i%10)
+			q.Pop()
+			i++
+		}
+	})
+}
+
+func BenchmarkRemoveParallel(b *testing.B) {
+	q := pqueue.New[int](0)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			it, _ := q.Push(i, i%10)
+			q.Remove(it)
+			i++
+		}
+	})
+}
@@ -0,0 +1,69 @@
+// Command serve runs a pool on made-up work and exports its metrics for
+// Prometheus to scrape:
+//
+//	go run ./examples/serve -addr :9090
+//	curl localhost:9090/metrics
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"workerpool"
+	"workerpool/prom"
+)
+
+var (
+	addr    = flag.String("addr", ":9090", "address to listen on")
+	workers = flag.Int("workers", 4, "number of workers")
+	rate    = flag.Duration("every", 20*time.Millisecond, "time between submitted tasks")
+)
+
+func main() {
+	flag.Parse()
+
+	c := prom.NewCollector("serve")
+	p := workerpool.New(*workers,
+		workerpool.WithName("serve"),
+		workerpool.WithMiddleware(c.Middleware()),
+	)
+	defer p.Shutdown()
+	c.Watch(p)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c, collectors.NewGoCollector())
+
+	// Futures keep the failures out of Wait, which this pool never
+	// calls; the metrics are the only place they show up.
+	go func() {
+		for range time.Tick(*rate) {
+			workerpool.SubmitFuture(p, work)
+		}
+	}()
+
+	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	log.Printf("serving metrics on %s/metrics", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+// work sleeps for a while and fails now and then.
+func work(ctx context.Context) (struct{}, error) {
+	select {
+	case <-time.After(time.Duration(rand.ExpFloat64() * float64(50*time.Millisecond))):
+	case <-ctx.Done():
+		return struct{}{}, ctx.Err()
+	}
+	if rand.IntN(10) == 0 {
+		return struct{}{}, errors.New("unlucky")
+	}
+	return struct{}{}, nil
+}
@@ -0,0 +1,139 @@
+// Package prom exports a workerpool.Pool's metrics as Prometheus
+// collectors. It is a module of its own so that the pool itself does not
+// depend on the Prometheus client.
+//
+// A Collector reads the pool's Stats each time it is scraped, and times
+// tasks through a Middleware:
+//
+//	c := prom.NewCollector("thumbnail")
+//	p := workerpool.New(8, workerpool.WithMiddleware(c.Middleware()))
+//	c.Watch(p)
+//	prometheus.MustRegister(c)
+package prom
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"workerpool"
+)
+
+// Collector is a prometheus.Collector for one pool. To export several
+// pools, use a Collector each and register them with different labels,
+// for instance with prometheus.WrapRegistererWith.
+type Collector struct {
+	mu   sync.Mutex
+	pool *workerpool.Pool
+
+	workers   *prometheus.Desc
+	busy      *prometheus.Desc
+	queued    *prometheus.Desc
+	submitted *prometheus.Desc
+	completed *prometheus.Desc
+	failed    *prometheus.Desc
+	dropped   *prometheus.Desc
+	retried   *prometheus.Desc
+	waitTime  *prometheus.Desc
+	runTime   *prometheus.Desc
+
+	duration prometheus.Histogram
+}
+
+// NewCollector returns a Collector whose metric names start with
+// namespace, or with "workerpool" if namespace is empty. It reports only
+// the task duration histogram until Watch gives it a pool.
+func NewCollector(namespace string) *Collector {
+	if namespace == "" {
+		namespace = "workerpool"
+	}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil)
+	}
+	return &Collector{
+		workers:   desc("workers", "Number of workers the pool is sized for."),
+		busy:      desc("workers_busy", "Number of workers running a task."),
+		queued:    desc("queue_depth", "Number of tasks waiting for a worker."),
+		submitted: desc("tasks_submitted_total", "Tasks accepted by the pool."),
+		completed: desc("tasks_completed_total", "Tasks that ran to completion, successfully or not."),
+		failed:    desc("tasks_failed_total", "Completed tasks that returned an error or panicked."),
+		dropped:   desc("tasks_dropped_total", "Tasks discarded from the queue without running."),
+		retried:   desc("tasks_retried_total", "Failed runs that were scheduled to run again."),
+		waitTime:  desc("task_wait_seconds_total", "Total time tasks spent queued."),
+		runTime:   desc("task_run_seconds_total", "Total time tasks spent running."),
+
+		duration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "task_duration_seconds",
+			Help:      "Time each run of a task took, retries counted separately.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+	}
+}
+
+// Watch makes c report p's Stats. It replaces any pool watched before.
+func (c *Collector) Watch(p *workerpool.Pool) {
+	c.mu.Lock()
+	c.pool = p
+	c.mu.Unlock()
+}
+
+// Middleware returns a workerpool.Middleware that records how long each
+// run of a task takes in c's task duration histogram.
+func (c *Collector) Middleware() workerpool.Middleware {
+	return func(next workerpool.Task) workerpool.Task {
+		return func(ctx context.Context) error {
+			start := time.Now()
+			// Deferred, so that panicking runs are timed too.
+			defer func() { c.duration.Observe(time.Since(start).Seconds()) }()
+			return next(ctx)
+		}
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.workers
+	ch <- c.busy
+	ch <- c.queued
+	ch <- c.submitted
+	ch <- c.completed
+	ch <- c.failed
+	ch <- c.dropped
+	ch <- c.retried
+	ch <- c.waitTime
+	ch <- c.runTime
+	c.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.duration.Collect(ch)
+
+	c.mu.Lock()
+	p := c.pool
+	c.mu.Unlock()
+	if p == nil {
+		return
+	}
+
+	s := p.Stats()
+	gauge := func(d *prometheus.Desc, v int) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, float64(v))
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(c.workers, s.Workers)
+	gauge(c.busy, s.Busy)
+	gauge(c.queued, s.Queued)
+	counter(c.submitted, float64(s.Submitted))
+	counter(c.completed, float64(s.Completed))
+	counter(c.failed, float64(s.Failed))
+	counter(c.dropped, float64(s.Dropped))
+	counter(c.retried, float64(s.Retried))
+	counter(c.waitTime, s.WaitTime.Seconds())
+	counter(c.runTime, s.RunTime.Seconds())
+}
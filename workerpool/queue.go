@@ -0,0 +1,246 @@
+package workerpool
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"time"
+
+	"workerpool/pqueue"
+)
+
+// ErrQueueFull is returned by Submit when the queue is full and the
+// overflow policy is Reject. Tasks discarded by DropNewest or DropOldest
+// are dropped with this error too.
+var ErrQueueFull = errors.New("workerpool: queue is full")
+
+// OverflowPolicy decides what happens when a task is submitted to a full
+// queue; see WithQueueCapacity.
+type OverflowPolicy int
+
+const (
+	// Block makes Submit wait until there is room. SubmitContext gives up
+	// when its context is done.
+	Block OverflowPolicy = iota
+	// DropNewest discards the task being submitted. Submit still returns
+	// nil.
+	DropNewest
+	// DropOldest discards the task that has been queued longest to make
+	// room, whatever its priority. With a custom queue that has no Oldest
+	// method, it discards the next task to run instead.
+	DropOldest
+	// Reject makes Submit return ErrQueueFull.
+	Reject
+)
+
+// Job is a submitted task waiting in a Queue.
+type Job struct {
+	task     Task
+	ctx      context.Context
+	priority int
+	queued   time.Time
+
+	// id is assigned when the job is accepted, and rec is its status
+	// record if the pool keeps them.
+	id  TaskID
+	rec *record
+
+	// weight is the number of slots the job takes; 0 means 1.
+	weight int
+
+	// attempt counts the times the task has been run.
+	attempt int
+
+	// once jobs are never retried.
+	once bool
+
+	// finish, if set, is called exactly once when the job leaves the pool
+	// for good: with the task's final error, or with the reason it was
+	// dropped without running.
+	finish func(err error)
+
+	// silent jobs report their outcome through finish alone; apart from
+	// panics, Wait does not see their errors.
+	silent bool
+
+	// watch is the watch on ctx the job is registered with while it is
+	// queued or waiting to be retried, if ctx can be cancelled.
+	watch      *watch
+	watchIndex int
+}
+
+// Context returns the context the job was submitted with.
+func (j *Job) Context() context.Context {
+	return j.ctx
+}
+
+// Priority returns the priority the job was submitted with; higher runs
+// first.
+func (j *Job) Priority() int {
+	return j.priority
+}
+
+// Queue holds jobs until a worker is free. The pool serialises every call,
+// so implementations need no locking of their own.
+//
+// When a job's context is cancelled while it is queued, the pool calls
+// Remove straight away rather than waiting for a worker to pop the job and
+// notice, so a custom queue only has to support removal to get prompt
+// cleanup of abandoned work.
+//
+// A queue may also have a method
+//
+//	Clear() []*Job
+//
+// that empties it at once, returning the jobs in any order. The pool uses
+// it instead of popping job by job when it discards the whole queue. And
+// WithReservedWorkers and WithSlots need
+//
+//	Peek() *Job
+//
+// returning the job Pop would return, or nil, without removing it. The
+// DropOldest policy uses
+//
+//	Oldest() *Job
+//
+// returning the job pushed longest ago, or nil, without removing it.
+type Queue interface {
+	// Push adds a job to the queue.
+	Push(j *Job)
+	// Pop removes and returns the next job to run, or nil if the queue is
+	// empty.
+	Pop() *Job
+	// Remove takes j out of the queue, reporting whether it was there.
+	Remove(j *Job) bool
+	// Len returns the number of queued jobs.
+	Len() int
+}
+
+// NewPriority returns the default Queue: highest priority first, and first
+// in, first out among equal priorities. Push, Pop and Remove are O(log n).
+func NewPriority() Queue {
+	return &priorityQueue{
+		jobs:  pqueue.New[*Job](0),
+		items: make(map[*Job]queued),
+	}
+}
+
+type priorityQueue struct {
+	jobs  *pqueue.Queue[*Job]
+	items map[*Job]queued
+	order list.List // the jobs in the order they were pushed, for Oldest
+}
+
+// queued is where a job is held in a priorityQueue.
+type queued struct {
+	item  *pqueue.Item[*Job]
+	order *list.Element
+}
+
+func (q *priorityQueue) Push(j *Job) {
+	// The queue is unbounded, so Push cannot fail.
+	it, _ := q.jobs.Push(j, j.priority)
+	q.items[j] = queued{item: it, order: q.order.PushBack(j)}
+}
+
+func (q *priorityQueue) Pop() *Job {
+	j, _, ok := q.jobs.Pop()
+	if !ok {
+		return nil
+	}
+	q.order.Remove(q.items[j].order)
+	delete(q.items, j)
+	return j
+}
+
+func (q *priorityQueue) Remove(j *Job) bool {
+	e, ok := q.items[j]
+	if !ok {
+		return false
+	}
+	q.order.Remove(e.order)
+	delete(q.items, j)
+	return q.jobs.Remove(e.item)
+}
+
+func (q *priorityQueue) Len() int {
+	return q.jobs.Len()
+}
+
+func (q *priorityQueue) Peek() *Job {
+	j, _, _ := q.jobs.Peek()
+	return j
+}
+
+func (q *priorityQueue) Oldest() *Job {
+	if e := q.order.Front(); e != nil {
+		return e.Value.(*Job)
+	}
+	return nil
+}
+
+func (q *priorityQueue) Clear() []*Job {
+	clear(q.items)
+	q.order.Init()
+	return q.jobs.Clear()
+}
+
+// NewFIFO returns a Queue that ignores priorities: first in, first out,
+// unbounded, with constant time operations.
+func NewFIFO() Queue {
+	return &fifo{elems: make(map[*Job]*list.Element)}
+}
+
+type fifo struct {
+	jobs  list.List
+	elems map[*Job]*list.Element
+}
+
+func (q *fifo) Push(j *Job) {
+	q.elems[j] = q.jobs.PushBack(j)
+}
+
+func (q *fifo) Pop() *Job {
+	e := q.jobs.Front()
+	if e == nil {
+		return nil
+	}
+	j := q.jobs.Remove(e).(*Job)
+	delete(q.elems, j)
+	return j
+}
+
+func (q *fifo) Remove(j *Job) bool {
+	e, ok := q.elems[j]
+	if !ok {
+		return false
+	}
+	q.jobs.Remove(e)
+	delete(q.elems, j)
+	return true
+}
+
+func (q *fifo) Len() int {
+	return q.jobs.Len()
+}
+
+func (q *fifo) Peek() *Job {
+	if e := q.jobs.Front(); e != nil {
+		return e.Value.(*Job)
+	}
+	return nil
+}
+
+func (q *fifo) Oldest() *Job {
+	return q.Peek()
+}
+
+func (q *fifo) Clear() []*Job {
+	jobs := make([]*Job, 0, q.jobs.Len())
+	for e := q.jobs.Front(); e != nil; e = e.Next() {
+		jobs = append(jobs, e.Value.(*Job))
+	}
+	q.jobs.Init()
+	clear(q.elems)
+	return jobs
+}
@@ -0,0 +1,16 @@
+package workerpool_test
+
+import (
+	"testing"
+
+	"workerpool"
+	"workerpool/queuetest"
+)
+
+func TestPriorityQueue(t *testing.T) {
+	queuetest.RunFIFO(t, workerpool.NewPriority)
+}
+
+func TestFIFOQueue(t *testing.T) {
+	queuetest.RunFIFO(t, workerpool.NewFIFO)
+}
@@ -0,0 +1,279 @@
+// Package queuetest is a conformance suite for workerpool.Queue
+// implementations. Call it from a test in the package that defines the
+// queue:
+//
+//	func TestQueue(t *testing.T) {
+//		queuetest.Run(t, func() workerpool.Queue { return myqueue.New() })
+//	}
+//
+// The suite drives the queue through a real workerpool.Pool, the way it
+// will be used, observing it only through the pool, and checks that every
+// job is delivered exactly once under concurrent submitters, that Len
+// tracks the queued jobs, that cancelled jobs are removed promptly, and
+// that shutdown drains and cancellation discards the queue. Queues
+// promising first-in first-out order should use RunFIFO, which also checks
+// ordering. Queue has no acknowledgements, so redelivery is not part of the
+// contract and not checked.
+package queuetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"workerpool"
+)
+
+// Run runs the conformance suite against queues made by newQueue. Each
+// subtest gets a fresh queue.
+func Run(t *testing.T, newQueue func() workerpool.Queue) {
+	t.Run("ExactlyOnce", func(t *testing.T) { testExactlyOnce(t, newQueue()) })
+	t.Run("Len", func(t *testing.T) { testLen(t, newQueue()) })
+	t.Run("Remove", func(t *testing.T) { testRemove(t, newQueue()) })
+	t.Run("ShutdownDrains", func(t *testing.T) { testShutdownDrains(t, newQueue()) })
+	t.Run("CancelDiscards", func(t *testing.T) { testCancelDiscards(t, newQueue()) })
+}
+
+// RunFIFO is Run plus a check that jobs come out in the order they were
+// pushed.
+func RunFIFO(t *testing.T, newQueue func() workerpool.Queue) {
+	Run(t, newQueue)
+	t.Run("Order", func(t *testing.T) { testOrder(t, newQueue()) })
+}
+
+// timeout bounds every wait in the suite, so a broken queue fails instead
+// of hanging.
+const timeout = 5 * time.Second
+
+// gate is a task that blocks a worker until released, so the suite can
+// fill the queue behind it.
+type gate struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newGate() *gate {
+	return &gate{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (g *gate) task(context.Context) error {
+	close(g.started)
+	<-g.release
+	return nil
+}
+
+// block occupies the only worker of p.
+func (g *gate) block(t *testing.T, p *workerpool.Pool) {
+	t.Helper()
+	if err := p.Submit(g.task); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case <-g.started:
+	case <-time.After(timeout):
+		t.Fatal("blocking task never started")
+	}
+}
+
+// eventually polls cond until it holds or the suite's timeout passes.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testExactlyOnce(t *testing.T, q workerpool.Queue) {
+	const submitters, perSubmitter = 8, 500
+
+	p := workerpool.New(4, workerpool.WithQueue(q))
+	defer p.Shutdown()
+
+	var mu sync.Mutex
+	runs := make(map[int]int)
+
+	var wg sync.WaitGroup
+	for s := 0; s < submitters; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			for i := 0; i < perSubmitter; i++ {
+				id := s*perSubmitter + i
+				if err := p.Submit(func(context.Context) error {
+					mu.Lock()
+					runs[id]++
+					mu.Unlock()
+					return nil
+				}); err != nil {
+					t.Errorf("Submit: %v", err)
+					return
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	for id := 0; id < submitters*perSubmitter; id++ {
+		if n := runs[id]; n != 1 {
+			t.Errorf("job %d ran %d times, want once", id, n)
+		}
+	}
+	if got := p.Stats().Queued; got != 0 {
+		t.Errorf("Len after draining = %d, want 0", got)
+	}
+}
+
+func testLen(t *testing.T, q workerpool.Queue) {
+	p := workerpool.New(1, workerpool.WithQueue(q))
+	defer p.Shutdown()
+
+	g := newGate()
+	g.block(t, p)
+	for i := 1; i <= 10; i++ {
+		p.Submit(func(context.Context) error { return nil })
+		if got := p.Stats().Queued; got != i {
+			t.Fatalf("Len after %d pushes = %d", i, got)
+		}
+	}
+
+	close(g.release)
+	p.Wait()
+	if got := p.Stats().Queued; got != 0 {
+		t.Errorf("Len after draining = %d, want 0", got)
+	}
+}
+
+func testRemove(t *testing.T, q workerpool.Queue) {
+	p := workerpool.New(1, workerpool.WithQueue(q))
+	defer p.Shutdown()
+
+	g := newGate()
+	g.block(t, p)
+
+	var mu sync.Mutex
+	ran := make(map[int]bool)
+	cancels := make([]context.CancelFunc, 10)
+	for i := range cancels {
+		i := i
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels[i] = cancel
+		p.SubmitContext(ctx, func(context.Context) error {
+			mu.Lock()
+			ran[i] = true
+			mu.Unlock()
+			return nil
+		})
+	}
+	for i := 0; i < len(cancels); i += 2 {
+		cancels[i]()
+	}
+
+	// The worker is still blocked, so only Remove can shrink the queue.
+	eventually(t, "cancelled jobs to be removed", func() bool { return p.Stats().Queued == 5 })
+
+	close(g.release)
+	p.Wait()
+	for i := range cancels {
+		if want := i%2 == 1; ran[i] != want {
+			t.Errorf("job %d ran = %v, want %v", i, ran[i], want)
+		}
+		cancels[i]()
+	}
+}
+
+func testShutdownDrains(t *testing.T, q workerpool.Queue) {
+	p := workerpool.New(1, workerpool.WithQueue(q))
+
+	g := newGate()
+	g.block(t, p)
+
+	var mu sync.Mutex
+	var ran int
+	for i := 0; i < 20; i++ {
+		p.Submit(func(context.Context) error {
+			mu.Lock()
+			ran++
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.Shutdown()
+		close(done)
+	}()
+	close(g.release)
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatal("Shutdown did not return")
+	}
+	if ran != 20 {
+		t.Errorf("%d of 20 queued jobs ran before Shutdown returned", ran)
+	}
+}
+
+func testCancelDiscards(t *testing.T, q workerpool.Queue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := workerpool.NewWithContext(ctx, 1, workerpool.WithQueue(q))
+	defer p.Shutdown()
+
+	g := newGate()
+	g.block(t, p)
+
+	var mu sync.Mutex
+	var ran int
+	for i := 0; i < 20; i++ {
+		p.Submit(func(context.Context) error {
+			mu.Lock()
+			ran++
+			mu.Unlock()
+			return nil
+		})
+	}
+	cancel()
+	eventually(t, "the queue to be discarded", func() bool { return p.Stats().Queued == 0 })
+	close(g.release)
+	p.Wait()
+
+	if ran != 0 {
+		t.Errorf("%d jobs ran after the pool was cancelled", ran)
+	}
+	if got := p.Stats().Dropped; got != 20 {
+		t.Errorf("Dropped = %d, want 20", got)
+	}
+}
+
+func testOrder(t *testing.T, q workerpool.Queue) {
+	p := workerpool.New(1, workerpool.WithQueue(q))
+	defer p.Shutdown()
+
+	g := newGate()
+	g.block(t, p)
+
+	var order []int
+	for i := 0; i < 100; i++ {
+		i := i
+		p.Submit(func(context.Context) error {
+			order = append(order, i)
+			return nil
+		})
+	}
+	close(g.release)
+	p.Wait()
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("job %d ran in position %d", got, i)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package workerpool
+
+import "time"
+
+// limiter is a token bucket: it holds up to burst tokens and gains rate of
+// them a second. It is guarded by the pool's mutex.
+type limiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	timer  *time.Timer // wakes the workers once a token is due
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// take takes a token if there is one, and otherwise returns how long until
+// there will be.
+func (l *limiter) take(now time.Time) time.Duration {
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// refund gives back a token that was taken for nothing.
+func (l *limiter) refund() {
+	l.tokens = min(l.burst, l.tokens+1)
+}
+
+// throttledLocked reports whether the workers must wait before the next
+// job may start, and if so makes sure one of them is woken up in time.
+// p.mu must be held.
+func (p *Pool) throttledLocked() bool {
+	if p.limit == nil {
+		return false
+	}
+	d := p.limit.take(time.Now())
+	if d == 0 {
+		return false
+	}
+	if p.limit.timer == nil {
+		p.limit.timer = time.AfterFunc(d, func() {
+			p.mu.Lock()
+			p.limit.timer = nil
+			p.cond.Broadcast()
+			p.mu.Unlock()
+		})
+	}
+	return true
+}
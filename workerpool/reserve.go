@@ -0,0 +1,41 @@
+package workerpool
+
+// peeker is implemented by queues that can show their next job without
+// popping it, which worker reservation needs.
+type peeker interface {
+	Peek() *Job
+}
+
+// WithReservedWorkers keeps n of the pool's workers for jobs of priority
+// minPriority and up: lower-priority jobs never occupy more than the other
+// workers, so however much of them is queued, there is headroom for urgent
+// work. Urgent jobs may use any worker.
+//
+// The reservation is a count, not a set of dedicated workers, and it is
+// kept as the pool is resized; with no more than n workers, lower-priority
+// jobs wait. It needs a queue that puts higher priorities first, like the
+// default one, and that can Peek at its next job: New panics otherwise.
+func WithReservedWorkers(n, minPriority int) Option {
+	if n < 1 {
+		panic("workerpool: must reserve at least one worker")
+	}
+	return func(c *config) {
+		c.reserved = n
+		c.reservedFor = minPriority
+	}
+}
+
+// holdBackLocked reports whether the next queued job must wait because
+// the only workers it could use are reserved. p.mu must be held.
+func (p *Pool) holdBackLocked() bool {
+	if p.reserved == 0 {
+		return false
+	}
+	j := p.queue.(peeker).Peek()
+	return j != nil && !p.urgent(j) && p.busyLow >= p.size-p.reserved
+}
+
+// urgent reports whether j may use the reserved workers.
+func (p *Pool) urgent(j *Job) bool {
+	return j.priority >= p.reservedFor
+}
@@ -0,0 +1,63 @@
+package workerpool_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"workerpool"
+)
+
+const urgent = 10
+
+func TestReservedWorkers(t *testing.T) {
+	p := workerpool.New(4, workerpool.WithReservedWorkers(1, urgent))
+	var running, most atomic.Int32
+	for range 12 {
+		p.Submit(func(context.Context) error {
+			r := running.Add(1)
+			defer running.Add(-1)
+			for m := most.Load(); r > m && !most.CompareAndSwap(m, r); m = most.Load() {
+			}
+			time.Sleep(2 * time.Millisecond)
+			return nil
+		})
+	}
+	p.Shutdown()
+	if m := most.Load(); m != 3 {
+		t.Errorf("up to %d low-priority tasks ran at a time, want 3 of the 4 workers", m)
+	}
+}
+
+func TestReservedWorkersUrgent(t *testing.T) {
+	p := workerpool.New(3, workerpool.WithReservedWorkers(1, urgent))
+	defer p.Shutdown()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	for range 10 {
+		p.Submit(func(context.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+	}
+	defer close(release)
+	<-started
+	<-started
+
+	ran := make(chan struct{})
+	p.SubmitWithPriority(func(context.Context) error {
+		close(ran)
+		return nil
+	}, urgent)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("urgent task did not start while low-priority ones held their workers")
+	}
+	if s := p.Stats(); s.Queued != 8 {
+		t.Errorf("Queued = %d, want the 8 low-priority tasks left", s.Queued)
+	}
+}
@@ -0,0 +1,50 @@
+package workerpool
+
+import "time"
+
+// retryableLocked reports whether j should run again after failing with
+// err. p.mu must be held.
+func (p *Pool) retryableLocked(j *Job, err error) bool {
+	if err == nil || j.once || j.attempt >= p.retryAttempts {
+		return false
+	}
+	if _, ok := err.(*PanicError); ok {
+		return false
+	}
+	return p.ctx.Err() == nil && j.ctx.Err() == nil
+}
+
+// retryLocked puts j aside until its backoff has passed and then queues it
+// again. Until then it can still be cancelled through its context, or
+// discarded with the pool. p.mu must be held.
+func (p *Pool) retryLocked(j *Job) {
+	p.stats.Retried++
+	p.pendingLocked(j)
+
+	var delay time.Duration
+	if p.retryBackoff != nil {
+		delay = p.retryBackoff.Delay(j.attempt)
+	}
+	p.retries[j] = time.AfterFunc(delay, func() { p.requeue(j) })
+	p.watchLocked(j)
+}
+
+// requeue puts a job back in the queue once its backoff is over. Retries
+// go in regardless of the queue's capacity: they were accepted already.
+func (p *Pool) requeue(j *Job) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.retries[j]; !ok {
+		return // removed or discarded in the meantime
+	}
+	delete(p.retries, j)
+	j.queued = time.Now()
+	p.queue.Push(j)
+	if p.closed && len(p.retries) == 0 {
+		// Idle workers of a closed pool were only waiting for this.
+		p.cond.Broadcast()
+	} else {
+		p.cond.Signal()
+	}
+}
@@ -0,0 +1,109 @@
+package workerpool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"workerpool"
+	"workerpool/backoff"
+)
+
+var errFlaky = errors.New("flaky")
+
+func TestRetry(t *testing.T) {
+	p := workerpool.New(1, workerpool.WithRetry(3, backoff.Constant(time.Millisecond)))
+	defer p.Shutdown()
+
+	var runs atomic.Int32
+	p.Submit(func(context.Context) error {
+		if runs.Add(1) < 3 {
+			return errFlaky
+		}
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		t.Errorf("Wait = %v, want nil once the third run succeeded", err)
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("task ran %d times, want 3", n)
+	}
+	if s := p.Stats(); s.Retried != 2 || s.Failed != 0 {
+		t.Errorf("Retried = %d, Failed = %d, want 2 and 0", s.Retried, s.Failed)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	p := workerpool.New(1, workerpool.WithRetry(3, nil))
+	defer p.Shutdown()
+
+	var runs atomic.Int32
+	p.Submit(func(context.Context) error {
+		runs.Add(1)
+		return errFlaky
+	})
+	if err := p.Wait(); !errors.Is(err, errFlaky) {
+		t.Errorf("Wait = %v, want %v", err, errFlaky)
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("task ran %d times, want 3", n)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	p := workerpool.New(1, workerpool.WithRetry(2, backoff.Constant(delay)))
+	defer p.Shutdown()
+
+	var first time.Time
+	p.Submit(func(context.Context) error {
+		if first.IsZero() {
+			first = time.Now()
+			return errFlaky
+		}
+		if d := time.Since(first); d < delay {
+			t.Errorf("ran again after %v, want at least %v", d, delay)
+		}
+		return nil
+	})
+	p.Wait()
+}
+
+func TestRetrySkipped(t *testing.T) {
+	t.Run("Panic", func(t *testing.T) {
+		p := workerpool.New(1, workerpool.WithRetry(3, nil))
+		defer p.Shutdown()
+
+		var runs atomic.Int32
+		p.Submit(func(context.Context) error {
+			runs.Add(1)
+			panic("boom")
+		})
+		var perr *workerpool.PanicError
+		if err := p.Wait(); !errors.As(err, &perr) {
+			t.Errorf("Wait = %v, want a PanicError", err)
+		}
+		if n := runs.Load(); n != 1 {
+			t.Errorf("task ran %d times, want 1", n)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		p := workerpool.New(1, workerpool.WithRetry(3, nil))
+		defer p.Shutdown()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var runs atomic.Int32
+		p.SubmitContext(ctx, func(context.Context) error {
+			runs.Add(1)
+			cancel()
+			return errFlaky
+		})
+		p.Wait()
+		if n := runs.Load(); n != 1 {
+			t.Errorf("task ran %d times, want 1", n)
+		}
+	})
+}
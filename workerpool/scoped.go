@@ -0,0 +1,68 @@
+package workerpool
+
+import "context"
+
+// Scoped returns a short-lived pool for a single request or task, the "spin
+// the pool up per task" approach from the workerpool4 notes.
+//
+// The scoped pool runs at most n of its tasks at a time, but it does not add
+// workers of its own: every task is executed on one of parent's workers, so
+// the scope draws from the parent's budget instead of growing it. A task's
+// context is cancelled when either the scope or the parent stops. The scoped
+// pool stops when ctx is done, after which Submit returns ctx's error;
+// calling Shutdown earlier is fine and waits as usual.
+//
+// A scope opened from a task of parent, with that task's context, is
+// different: waiting for work queued behind the very worker that waits,
+// it would deadlock once every worker of parent did the same. Such a scope
+// runs its tasks itself instead, one at a time, in place of the worker its
+// caller holds, whatever n is.
+func Scoped(ctx context.Context, parent *Pool, n int, opts ...Option) *Pool {
+	if onWorkerOf(ctx, parent) {
+		p := newPool(ctx, 1, nil, opts)
+		p.standsIn = parent
+		return p
+	}
+
+	var p *Pool
+	p = newPool(ctx, n, func(ctx context.Context, task Task) error {
+		done := make(chan struct{})
+		var taskErr error
+		err := parent.enqueue(&Job{
+			task: func(ctx context.Context) error {
+				// The error, or panic, belongs to the scope rather
+				// than the parent, and the scope does its own
+				// retrying.
+				taskErr = p.call(ctx, task)
+				return nil
+			},
+			ctx: ctx,
+			finish: func(err error) {
+				if err != nil {
+					// The parent dropped it, or the scope
+					// ended while it was still queued there.
+					taskErr = err
+				}
+				close(done)
+			},
+		})
+		if err != nil {
+			return err
+		}
+		<-done
+		return taskErr
+	}, opts)
+	return p
+}
+
+// onWorkerOf reports whether ctx is that of a task running on one of p's
+// workers, or on a scope standing in for one.
+func onWorkerOf(ctx context.Context, p *Pool) bool {
+	w, _ := ctx.Value(workerKey{}).(workerInfo)
+	for q := w.pool; q != nil; q = q.standsIn {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
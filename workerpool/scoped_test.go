@@ -0,0 +1,105 @@
+package workerpool_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"workerpool"
+)
+
+func TestScoped(t *testing.T) {
+	parent := workerpool.New(4, workerpool.WithName("parent"))
+	defer parent.Shutdown()
+
+	const n = 2
+	scope := workerpool.Scoped(context.Background(), parent, n)
+	var running, most atomic.Int32
+	for range 10 {
+		scope.Submit(func(ctx context.Context) error {
+			if name := workerpool.WorkerName(ctx); !strings.HasPrefix(name, "parent-") {
+				t.Errorf("task runs on %q, not a worker of the parent", name)
+			}
+			r := running.Add(1)
+			defer running.Add(-1)
+			for m := most.Load(); r > m && !most.CompareAndSwap(m, r); m = most.Load() {
+			}
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+	}
+	scope.Shutdown()
+	if m := most.Load(); m != n {
+		t.Errorf("at most %d tasks ran at a time, want %d", m, n)
+	}
+	if s := scope.Stats(); s.Completed != 10 {
+		t.Errorf("scope completed %d tasks, want 10", s.Completed)
+	}
+}
+
+// Every worker of the parent opening a scope and waiting for it must not
+// deadlock.
+func TestScopedFromTask(t *testing.T) {
+	parent := workerpool.New(2)
+	var (
+		ran  atomic.Int32
+		done sync.WaitGroup
+	)
+	for range 2 {
+		done.Add(1)
+		parent.Submit(func(ctx context.Context) error {
+			defer done.Done()
+			scope := workerpool.Scoped(ctx, parent, 2)
+			for range 3 {
+				scope.Submit(func(context.Context) error {
+					ran.Add(1)
+					return nil
+				})
+			}
+			scope.Shutdown()
+			return nil
+		})
+	}
+	wait(t, done.Wait)
+	parent.Shutdown()
+	if n := ran.Load(); n != 6 {
+		t.Errorf("scopes ran %d tasks, want 6", n)
+	}
+}
+
+func TestRunBatchFromTask(t *testing.T) {
+	p := workerpool.New(1)
+	reports := make(chan workerpool.BatchReport, 1)
+	p.Submit(func(ctx context.Context) error {
+		reports <- p.RunBatch(ctx, workerpool.Budget{}, []workerpool.Task{
+			func(context.Context) error { return nil },
+			func(context.Context) error { return nil },
+		})
+		return nil
+	})
+	var r workerpool.BatchReport
+	wait(t, func() { r = <-reports })
+	p.Shutdown()
+	if r.Ran != 2 || len(r.Skipped) != 0 {
+		t.Errorf("batch ran %d and skipped %v, want 2 and none", r.Ran, r.Skipped)
+	}
+}
+
+// wait fails t if f does not return within a second, leaving the pools
+// involved deadlocked.
+func wait(t *testing.T, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deadlocked")
+	}
+}
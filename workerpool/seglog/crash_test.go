@@ -0,0 +1,28 @@
+package seglog_test
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"workerpool/internal/crashtest"
+)
+
+// TestCrash is a short, deterministic version of cmd/seglogcrash.
+func TestCrash(t *testing.T) {
+	runs, crashes := 10, 20
+	if testing.Short() {
+		runs, crashes = 3, 10
+	}
+	for seed := int64(1); seed <= 4; seed++ {
+		t.Run(fmt.Sprint("seed", seed), func(t *testing.T) {
+			t.Parallel()
+			rng := rand.New(rand.NewSource(seed))
+			for run := 0; run < runs; run++ {
+				if err := crashtest.Torture(t.TempDir(), rng, crashes); err != nil {
+					t.Fatalf("run %d: %v", run, err)
+				}
+			}
+		})
+	}
+}
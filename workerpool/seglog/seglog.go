@@ -0,0 +1,669 @@
+// Package seglog is an append-only, segmented log for durably queueing
+// opaque entries until they are acknowledged.
+//
+// Every record is framed with its length and a CRC-32C checksum. Entries and
+// acknowledgements are both records, so nothing is ever rewritten in place:
+// the log rolls over to a new segment file once the current one reaches
+// Options.SegmentSize, and Compact reclaims space by carrying the entries
+// that are still pending forward to the active segment and deleting the
+// sealed ones.
+//
+// Every segment starts with a mark record holding the highest ID handed
+// out so far, so that IDs keep increasing across restarts even once
+// Compact has deleted every entry.
+//
+// On Open the segments are scanned to rebuild the set of pending entries. A
+// torn or corrupt record at the end of the newest segment is what a crash
+// mid-write leaves behind, so it is truncated away; a corrupt record in an
+// older segment ends the scan of that segment only. Either way is reported
+// by Recovered.
+//
+// # Durability
+//
+// If the process crashes, with any Sync policy, reopening the log yields
+// exactly the entries whose Append returned nil and whose Ack did not,
+// plus at most the one entry whose Append was in flight and minus at most
+// the one whose Ack was in flight. In other words no appended entry is
+// lost and at most one acknowledged entry comes back; consumers must
+// tolerate seeing that entry again. A crash during Compact loses and
+// duplicates nothing. cmd/seglogcrash checks this by crashing the log at
+// every failpoint (see Options.Failpoint).
+//
+// If the machine loses power, the same holds for SyncAlways. With
+// SyncInterval, writes from up to the last Options.SyncEvery may be lost
+// as well, and with SyncNever whatever the OS had not yet written back.
+package seglog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncPolicy says when the log calls fsync.
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs after every Append and Ack.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval fsyncs in the background every Options.SyncEvery.
+	SyncInterval
+	// SyncNever leaves writing back to the OS.
+	SyncNever
+)
+
+// Options configures a Log. The zero value is usable.
+type Options struct {
+	// SegmentSize is the size at which the log starts a new segment.
+	// Defaults to 16 MiB.
+	SegmentSize int64
+	// Sync is the fsync policy.
+	Sync SyncPolicy
+	// SyncEvery is the interval for SyncInterval. Defaults to one second.
+	SyncEvery time.Duration
+
+	// Failpoint, if set, is called at each point where a crash would be
+	// interesting: "append", "ack", "write" (part way through writing a
+	// record), "sync", "roll", "compact.copy" and "compact.remove". If it
+	// returns an error, the log acts as if the process died right there:
+	// it is closed without syncing, leaving a torn record behind in the
+	// case of "write", and the operation returns the error. This is for
+	// crash testing only.
+	Failpoint func(point string) error
+}
+
+// Recovery describes what Open had to repair.
+type Recovery struct {
+	// TruncatedBytes is the length of the torn tail removed from the
+	// newest segment.
+	TruncatedBytes int64
+	// CorruptSegments lists older segments whose scan ended early at a
+	// corrupt record; entries after that point are lost.
+	CorruptSegments []string
+}
+
+var (
+	// ErrClosed is returned by operations on a closed Log.
+	ErrClosed = errors.New("seglog: log is closed")
+	// ErrNotFound is returned for IDs that are not pending.
+	ErrNotFound = errors.New("seglog: no pending entry with that id")
+
+	errCorrupt = errors.New("seglog: corrupt record")
+)
+
+const (
+	recEntry byte = 1
+	recAck   byte = 2
+	recMark  byte = 3 // the ID is the highest handed out before the segment
+
+	// headerSize is the length and checksum preceding each record body,
+	// which is a type byte, an 8 byte ID and the payload.
+	headerSize = 8
+	bodyPrefix = 9
+
+	segmentExt = ".seg"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// location is where a pending entry's record lives.
+type location struct {
+	segment uint64
+	offset  int64 // of the record header
+	size    int   // of the payload
+}
+
+// Log is a segmented append-only log. It is safe for concurrent use.
+type Log struct {
+	dir  string
+	opts Options
+
+	mu        sync.Mutex
+	closed    bool
+	crash     error    // the failpoint error that abandoned the log, if any
+	segments  []uint64 // sequence numbers, oldest first; the last is active
+	files     map[uint64]*os.File
+	active    *os.File
+	size      int64 // of the active segment
+	nextID    uint64
+	pending   map[uint64]location
+	dirty     bool
+	recovered Recovery
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Open opens the log in dir, creating the directory if needed, and scans
+// the existing segments to rebuild the set of pending entries.
+func Open(dir string, opts Options) (*Log, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = 16 << 20
+	}
+	if opts.SyncEvery <= 0 {
+		opts.SyncEvery = time.Second
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		dir:     dir,
+		opts:    opts,
+		files:   make(map[uint64]*os.File),
+		pending: make(map[uint64]location),
+		nextID:  1,
+	}
+	if err := l.load(); err != nil {
+		l.closeFiles()
+		return nil, err
+	}
+
+	if opts.Sync == SyncInterval {
+		l.stop = make(chan struct{})
+		l.done = make(chan struct{})
+		go l.syncLoop(l.stop)
+	}
+	return l, nil
+}
+
+// load scans every segment in order, then opens the newest for appending.
+func (l *Log) load() error {
+	names, err := filepath.Glob(filepath.Join(l.dir, "*"+segmentExt))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		var seq uint64
+		base := strings.TrimSuffix(filepath.Base(name), segmentExt)
+		if _, err := fmt.Sscanf(base, "%016x", &seq); err != nil {
+			continue
+		}
+		l.segments = append(l.segments, seq)
+	}
+	sort.Slice(l.segments, func(i, j int) bool { return l.segments[i] < l.segments[j] })
+
+	for i, seq := range l.segments {
+		f, err := os.OpenFile(l.path(seq), os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		l.files[seq] = f
+
+		good, err := l.scan(seq, f)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, errCorrupt) {
+			return err
+		}
+		if i < len(l.segments)-1 {
+			l.recovered.CorruptSegments = append(l.recovered.CorruptSegments, filepath.Base(l.path(seq)))
+			continue
+		}
+		// A torn write at the very end of the log: cut it off so new
+		// records follow the last good one.
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if err := f.Truncate(good); err != nil {
+			return err
+		}
+		l.recovered.TruncatedBytes = fi.Size() - good
+	}
+
+	if len(l.segments) == 0 {
+		return l.roll()
+	}
+	seq := l.segments[len(l.segments)-1]
+	l.active = l.files[seq]
+	size, err := l.active.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	l.size = size
+	return nil
+}
+
+// scan replays the records of one segment into l.pending, returning the
+// offset just past the last good record.
+func (l *Log) scan(seq uint64, f *os.File) (int64, error) {
+	var off int64
+	for {
+		typ, id, payload, n, err := readRecord(f, off)
+		if err == io.EOF {
+			return off, nil
+		}
+		if err != nil {
+			return off, err
+		}
+		switch typ {
+		case recEntry:
+			// A later copy of the same ID, made by Compact, wins.
+			l.pending[id] = location{segment: seq, offset: off, size: len(payload)}
+		case recAck:
+			delete(l.pending, id)
+		}
+		if id >= l.nextID {
+			l.nextID = id + 1
+		}
+		off += n
+	}
+}
+
+// readRecord reads the record at off, returning its total length. It
+// returns io.EOF at a clean end of file and errCorrupt for a short or
+// damaged record.
+func readRecord(r io.ReaderAt, off int64) (typ byte, id uint64, payload []byte, n int64, err error) {
+	var hdr [headerSize]byte
+	if _, err := r.ReadAt(hdr[:], off); err != nil {
+		if err == io.EOF {
+			if m, _ := r.ReadAt(hdr[:1], off); m == 0 {
+				return 0, 0, nil, 0, io.EOF
+			}
+		}
+		return 0, 0, nil, 0, errCorrupt
+	}
+	size := binary.LittleEndian.Uint32(hdr[0:4])
+	sum := binary.LittleEndian.Uint32(hdr[4:8])
+	if size < bodyPrefix || size > 1<<30 {
+		return 0, 0, nil, 0, errCorrupt
+	}
+	body := make([]byte, size)
+	if _, err := r.ReadAt(body, off+headerSize); err != nil {
+		return 0, 0, nil, 0, errCorrupt
+	}
+	if crc32.Checksum(body, castagnoli) != sum {
+		return 0, 0, nil, 0, errCorrupt
+	}
+	typ = body[0]
+	if typ != recEntry && typ != recAck && typ != recMark {
+		return 0, 0, nil, 0, errCorrupt
+	}
+	id = binary.LittleEndian.Uint64(body[1:bodyPrefix])
+	return typ, id, body[bodyPrefix:], headerSize + int64(size), nil
+}
+
+// Recovered reports what Open had to repair.
+func (l *Log) Recovered() Recovery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.recovered
+}
+
+// Append writes data as a new entry and returns its ID. IDs increase
+// monotonically.
+func (l *Log) Append(data []byte) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, l.closedErr()
+	}
+	if err := l.failpoint("append", nil); err != nil {
+		return 0, err
+	}
+	id := l.nextID
+	if err := l.write(recEntry, id, data); err != nil {
+		return 0, err
+	}
+	l.nextID++
+	return id, l.synced()
+}
+
+// Ack marks the entry as processed, so it will not be replayed after a
+// restart and its space can be reclaimed by Compact.
+func (l *Log) Ack(id uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return l.closedErr()
+	}
+	if _, ok := l.pending[id]; !ok {
+		return ErrNotFound
+	}
+	if err := l.failpoint("ack", nil); err != nil {
+		return err
+	}
+	if err := l.write(recAck, id, nil); err != nil {
+		return err
+	}
+	return l.synced()
+}
+
+// write appends one record to the active segment, rolling over first if it
+// is full. l.mu must be held.
+func (l *Log) write(typ byte, id uint64, payload []byte) error {
+	if l.size > 0 && l.size+headerSize+bodyPrefix+int64(len(payload)) > l.opts.SegmentSize {
+		if err := l.roll(); err != nil {
+			return err
+		}
+	}
+
+	rec := make([]byte, headerSize+bodyPrefix+len(payload))
+	body := rec[headerSize:]
+	body[0] = typ
+	binary.LittleEndian.PutUint64(body[1:bodyPrefix], id)
+	copy(body[bodyPrefix:], payload)
+	binary.LittleEndian.PutUint32(rec[0:4], uint32(len(body)))
+	binary.LittleEndian.PutUint32(rec[4:8], crc32.Checksum(body, castagnoli))
+
+	if err := l.failpoint("write", func() {
+		l.active.WriteAt(rec[:len(rec)/2], l.size)
+	}); err != nil {
+		return err
+	}
+	if _, err := l.active.WriteAt(rec, l.size); err != nil {
+		return err
+	}
+	switch typ {
+	case recEntry:
+		l.pending[id] = location{segment: l.segments[len(l.segments)-1], offset: l.size, size: len(payload)}
+	case recAck:
+		delete(l.pending, id)
+	}
+	l.size += int64(len(rec))
+	l.dirty = true
+	return nil
+}
+
+// synced applies the SyncAlways policy after a write. l.mu must be held.
+func (l *Log) synced() error {
+	if l.opts.Sync != SyncAlways {
+		return nil
+	}
+	return l.syncLocked()
+}
+
+func (l *Log) syncLocked() error {
+	if !l.dirty {
+		return nil
+	}
+	if err := l.failpoint("sync", nil); err != nil {
+		return err
+	}
+	if err := l.active.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// roll seals the active segment and starts a new one. l.mu must be held.
+func (l *Log) roll() error {
+	if l.active != nil && l.opts.Sync != SyncNever {
+		if err := l.syncLocked(); err != nil {
+			return err
+		}
+	}
+
+	if err := l.failpoint("roll", nil); err != nil {
+		return err
+	}
+	var seq uint64
+	if n := len(l.segments); n > 0 {
+		seq = l.segments[n-1] + 1
+	}
+	f, err := os.OpenFile(l.path(seq), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, seq)
+	l.files[seq] = f
+	l.active = f
+	l.size = 0
+	if l.nextID > 1 {
+		if err := l.write(recMark, l.nextID-1, nil); err != nil {
+			return err
+		}
+	}
+	return l.syncDir()
+}
+
+// syncDir makes segment creation and removal durable.
+func (l *Log) syncDir() error {
+	if l.opts.Sync == SyncNever {
+		return nil
+	}
+	d, err := os.Open(l.dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Read returns the payload of a pending entry.
+func (l *Log) Read(id uint64) ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, l.closedErr()
+	}
+	loc, ok := l.pending[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	_, _, payload, _, err := readRecord(l.files[loc.segment], loc.offset)
+	if err == io.EOF {
+		err = errCorrupt
+	}
+	return payload, err
+}
+
+// Pending returns the IDs of all unacknowledged entries, oldest first.
+func (l *Log) Pending() []uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.pendingIDs()
+}
+
+func (l *Log) pendingIDs() []uint64 {
+	ids := make([]uint64, 0, len(l.pending))
+	for id := range l.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Replay calls fn for every unacknowledged entry, oldest first, stopping
+// at the first error fn returns. It is how a consumer picks up where it
+// left off after a restart.
+func (l *Log) Replay(fn func(id uint64, data []byte) error) error {
+	for _, id := range l.Pending() {
+		data, err := l.Read(id)
+		if errors.Is(err, ErrNotFound) {
+			continue // acked since we listed it
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(id, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compact reclaims the space used by acknowledged entries. Pending entries
+// in sealed segments are copied to the active segment, which is synced,
+// and then the sealed segments are deleted. A crash part way through
+// leaves at worst two copies of an entry, which Open resolves to one.
+func (l *Log) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return l.closedErr()
+	}
+	if err := l.roll(); err != nil {
+		return err
+	}
+	sealed := append([]uint64(nil), l.segments[:len(l.segments)-1]...)
+	if len(sealed) == 0 {
+		return nil
+	}
+
+	isSealed := make(map[uint64]bool, len(sealed))
+	for _, seq := range sealed {
+		isSealed[seq] = true
+	}
+	for _, id := range l.pendingIDs() {
+		loc := l.pending[id]
+		if !isSealed[loc.segment] {
+			continue
+		}
+		if err := l.failpoint("compact.copy", nil); err != nil {
+			return err
+		}
+		_, _, payload, _, err := readRecord(l.files[loc.segment], loc.offset)
+		if err != nil {
+			return fmt.Errorf("seglog: compacting entry %d: %w", id, errCorrupt)
+		}
+		if err := l.write(recEntry, id, payload); err != nil {
+			return err
+		}
+	}
+	// The copies must be durable before the originals go away.
+	if err := l.syncLocked(); err != nil {
+		return err
+	}
+
+	// write may have rolled over again, so only the segments that were
+	// sealed when we started are removed.
+	for _, seq := range sealed {
+		if err := l.failpoint("compact.remove", nil); err != nil {
+			return err
+		}
+		l.files[seq].Close()
+		delete(l.files, seq)
+		if err := os.Remove(l.path(seq)); err != nil {
+			return err
+		}
+	}
+	l.segments = l.segments[len(sealed):]
+	return l.syncDir()
+}
+
+// Sync flushes all writes to disk regardless of policy.
+func (l *Log) Sync() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return l.closedErr()
+	}
+	return l.syncLocked()
+}
+
+// Close syncs and closes the log.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		// Abandoned at a failpoint, perhaps, with the files closed
+		// already but the sync loop still to be waited for.
+		l.mu.Unlock()
+		if l.done != nil {
+			<-l.done
+		}
+		return nil
+	}
+	l.closed = true
+	var err error
+	if l.opts.Sync != SyncNever {
+		err = l.syncLocked()
+	}
+	l.stopSync()
+	l.mu.Unlock()
+
+	if l.done != nil {
+		<-l.done
+	}
+	if cerr := l.closeFiles(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// failpoint consults Options.Failpoint, and if it reports a crash runs
+// torn, if any, and then abandons the log. l.mu must be held.
+func (l *Log) failpoint(point string, torn func()) error {
+	if l.opts.Failpoint == nil {
+		return nil
+	}
+	err := l.opts.Failpoint(point)
+	if err == nil {
+		return nil
+	}
+	if torn != nil {
+		torn()
+	}
+	l.closed = true
+	l.crash = err
+	l.stopSync()
+	l.closeFiles()
+	return err
+}
+
+// closedErr is the error of operations on a closed log: ErrClosed, or the
+// failpoint error if that is how it was closed. l.mu must be held.
+func (l *Log) closedErr() error {
+	if l.crash != nil {
+		return l.crash
+	}
+	return ErrClosed
+}
+
+// stopSync tells the sync loop, if there is one, to exit. It may be called
+// from the loop itself. l.mu must be held, and l.closed set.
+func (l *Log) stopSync() {
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+}
+
+func (l *Log) closeFiles() error {
+	var err error
+	for _, f := range l.files {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (l *Log) syncLoop(stop <-chan struct{}) {
+	defer close(l.done)
+
+	t := time.NewTicker(l.opts.SyncEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			l.mu.Lock()
+			if !l.closed {
+				l.syncLocked()
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+func (l *Log) path(seq uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%016x%s", seq, segmentExt))
+}
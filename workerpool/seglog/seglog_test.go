@@ -0,0 +1,313 @@
+package seglog_test
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"workerpool/seglog"
+)
+
+func open(t *testing.T, dir string, opts seglog.Options) *seglog.Log {
+	t.Helper()
+	l, err := seglog.Open(dir, opts)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return l
+}
+
+func appendAll(t *testing.T, l *seglog.Log, payloads ...string) []uint64 {
+	t.Helper()
+	var ids []uint64
+	for _, p := range payloads {
+		id, err := l.Append([]byte(p))
+		if err != nil {
+			t.Fatalf("Append(%q): %v", p, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// contents returns the pending entries of l by ID.
+func contents(t *testing.T, l *seglog.Log) map[uint64]string {
+	t.Helper()
+	got := make(map[uint64]string)
+	if err := l.Replay(func(id uint64, data []byte) error {
+		got[id] = string(data)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	return got
+}
+
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	l := open(t, dir, seglog.Options{SegmentSize: 64})
+	ids := appendAll(t, l, "a", "bb", "ccc", "dddd")
+	if err := l.Ack(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Ack(ids[1]); !errors.Is(err, seglog.ErrNotFound) {
+		t.Errorf("second Ack = %v, want ErrNotFound", err)
+	}
+	l.Close()
+	if len(segments(t, dir)) < 2 {
+		t.Fatal("the log did not roll over")
+	}
+
+	l = open(t, dir, seglog.Options{SegmentSize: 64})
+	defer l.Close()
+	want := map[uint64]string{ids[0]: "a", ids[2]: "ccc", ids[3]: "dddd"}
+	if got := contents(t, l); !maps.Equal(got, want) {
+		t.Errorf("after reopening, pending = %v, want %v", got, want)
+	}
+	if r := l.Recovered(); r.TruncatedBytes != 0 || len(r.CorruptSegments) != 0 {
+		t.Errorf("clean reopen recovered %+v", r)
+	}
+}
+
+func TestIDsIncreaseAcrossCompactAndReopen(t *testing.T) {
+	dir := t.TempDir()
+	l := open(t, dir, seglog.Options{})
+	ids := appendAll(t, l, "a", "b")
+	for _, id := range ids {
+		if err := l.Ack(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	l = open(t, dir, seglog.Options{})
+	defer l.Close()
+	id, err := l.Append([]byte("c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id <= ids[1] {
+		t.Errorf("Append after compacting everything and reopening = %d, want more than %d", id, ids[1])
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	opts := seglog.Options{SegmentSize: 128}
+	l := open(t, dir, opts)
+	want := make(map[uint64]string)
+	for i := range 40 {
+		p := fmt.Sprintf("entry %d", i)
+		id := appendAll(t, l, p)[0]
+		if i%4 == 0 {
+			want[id] = p
+		} else if err := l.Ack(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := len(segments(t, dir))
+	if err := l.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if after := len(segments(t, dir)); after >= before {
+		t.Errorf("Compact left %d segments of %d", after, before)
+	}
+	if got := contents(t, l); !maps.Equal(got, want) {
+		t.Errorf("after Compact, pending = %v, want %v", got, want)
+	}
+	l.Close()
+
+	l = open(t, dir, opts)
+	defer l.Close()
+	if got := contents(t, l); !maps.Equal(got, want) {
+		t.Errorf("after Compact and reopening, pending = %v, want %v", got, want)
+	}
+}
+
+func TestTornTail(t *testing.T) {
+	dir := t.TempDir()
+	l := open(t, dir, seglog.Options{})
+	ids := appendAll(t, l, "kept", "also kept")
+	l.Close()
+
+	// Half a record, as a crash in the middle of a write leaves.
+	segs := segments(t, dir)
+	f, err := os.OpenFile(segs[len(segs)-1], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{20, 0, 0, 0, 1, 2, 3})
+	f.Close()
+
+	l = open(t, dir, seglog.Options{})
+	if r := l.Recovered(); r.TruncatedBytes != 7 {
+		t.Errorf("TruncatedBytes = %d, want 7", r.TruncatedBytes)
+	}
+	want := map[uint64]string{ids[0]: "kept", ids[1]: "also kept"}
+	if got := contents(t, l); !maps.Equal(got, want) {
+		t.Errorf("pending = %v, want %v", got, want)
+	}
+	// New records must follow the last good one, not the torn bytes.
+	id := appendAll(t, l, "after")[0]
+	l.Close()
+
+	l = open(t, dir, seglog.Options{})
+	defer l.Close()
+	want[id] = "after"
+	if got := contents(t, l); !maps.Equal(got, want) {
+		t.Errorf("after appending past the tear, pending = %v, want %v", got, want)
+	}
+}
+
+func TestCorruptRecord(t *testing.T) {
+	dir := t.TempDir()
+	opts := seglog.Options{SegmentSize: 64}
+	l := open(t, dir, opts)
+	ids := appendAll(t, l, "first", "second", "third", "fourth", "fifth")
+	l.Close()
+	segs := segments(t, dir)
+	if len(segs) < 3 {
+		t.Fatalf("want at least 3 segments, got %d", len(segs))
+	}
+
+	// Flip the last payload byte of the oldest segment's first record,
+	// so its checksum no longer matches.
+	corrupt(t, segs[0], len("first")+16)
+
+	l = open(t, dir, opts)
+	defer l.Close()
+	r := l.Recovered()
+	if !slices.Equal(r.CorruptSegments, []string{filepath.Base(segs[0])}) {
+		t.Errorf("CorruptSegments = %v, want [%s]", r.CorruptSegments, filepath.Base(segs[0]))
+	}
+	got := contents(t, l)
+	if _, ok := got[ids[0]]; ok {
+		t.Errorf("corrupt entry %d is still pending", ids[0])
+	}
+	if got[ids[len(ids)-1]] != "fifth" {
+		t.Errorf("entries of later segments were lost: %v", got)
+	}
+}
+
+// corrupt flips the byte at off in the file name.
+func corrupt(t *testing.T, name string, off int) {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[off] ^= 0xff
+	if err := os.WriteFile(name, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// points records the failpoints a log passes, without crashing it.
+type points struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (p *points) failpoint(point string) error {
+	p.mu.Lock()
+	p.seen = append(p.seen, point)
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *points) syncs() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, s := range p.seen {
+		if s == "sync" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSyncPolicies(t *testing.T) {
+	t.Run("always", func(t *testing.T) {
+		p := &points{}
+		l := open(t, t.TempDir(), seglog.Options{Sync: seglog.SyncAlways, Failpoint: p.failpoint})
+		defer l.Close()
+		id := appendAll(t, l, "x")[0]
+		if n := p.syncs(); n != 1 {
+			t.Errorf("%d syncs after Append, want 1", n)
+		}
+		l.Ack(id)
+		if n := p.syncs(); n != 2 {
+			t.Errorf("%d syncs after Ack, want 2", n)
+		}
+	})
+
+	t.Run("interval", func(t *testing.T) {
+		p := &points{}
+		l := open(t, t.TempDir(), seglog.Options{
+			Sync:      seglog.SyncInterval,
+			SyncEvery: time.Millisecond,
+			Failpoint: p.failpoint,
+		})
+		defer l.Close()
+		appendAll(t, l, "x")
+		if n := p.syncs(); n > 1 {
+			t.Errorf("%d syncs right after Append, want it left to the background", n)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for p.syncs() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("no background sync within 5s")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("never", func(t *testing.T) {
+		p := &points{}
+		dir := t.TempDir()
+		l := open(t, dir, seglog.Options{SegmentSize: 64, Sync: seglog.SyncNever, Failpoint: p.failpoint})
+		ids := appendAll(t, l, "one", "two", "three", "four")
+		l.Close()
+		if n := p.syncs(); n != 0 {
+			t.Errorf("%d syncs, want none", n)
+		}
+		// Written back by the OS all the same.
+		l = open(t, dir, seglog.Options{})
+		defer l.Close()
+		if got := contents(t, l); len(got) != len(ids) {
+			t.Errorf("pending = %v, want the %d entries", got, len(ids))
+		}
+	})
+}
+
+func TestClosed(t *testing.T) {
+	l := open(t, t.TempDir(), seglog.Options{})
+	l.Close()
+	if _, err := l.Append([]byte("x")); !errors.Is(err, seglog.ErrClosed) {
+		t.Errorf("Append on a closed log = %v, want ErrClosed", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTooHeavy is returned by SubmitWeighted for a task that weighs more
+// than all of the pool's slots.
+var ErrTooHeavy = errors.New("workerpool: task weighs more than the pool's slots")
+
+// WithSlots gives the pool a budget of total slots, shared by the running
+// tasks according to their weights: a task submitted with SubmitWeighted
+// takes as many slots as it weighs, and any other task takes one. A task
+// starts only when there are workers and slots free for it, so a heavy task
+// can count as several light ones.
+//
+// Tasks start in queue order: when the next one doesn't fit, those behind
+// it wait too, rather than starving it by filling every slot it frees.
+// Like WithReservedWorkers, it needs a queue that can Peek.
+func WithSlots(total int) Option {
+	if total < 1 {
+		panic("workerpool: need at least one slot")
+	}
+	return func(c *config) { c.slots = total }
+}
+
+// SubmitWeighted is like Submit, for a task that takes weight of the slots
+// given with WithSlots while it runs. It returns ErrTooHeavy if weight is
+// more than the total; without WithSlots, the weight is ignored.
+func (p *Pool) SubmitWeighted(task Task, weight int) error {
+	if weight < 1 {
+		panic("workerpool: weight must be at least 1")
+	}
+	if p.slots > 0 && weight > p.slots {
+		return ErrTooHeavy
+	}
+	return p.enqueue(&Job{task: task, ctx: context.Background(), weight: weight})
+}
+
+// Weight returns the number of slots the job takes while it runs.
+func (j *Job) Weight() int {
+	return max(j.weight, 1)
+}
+
+// outOfSlotsLocked reports whether the next queued job must wait for
+// running ones to give back slots. p.mu must be held.
+func (p *Pool) outOfSlotsLocked() bool {
+	if p.slots == 0 {
+		return false
+	}
+	j := p.queue.(peeker).Peek()
+	return j != nil && p.taken+j.Weight() > p.slots
+}
@@ -0,0 +1,88 @@
+package workerpool_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"workerpool"
+)
+
+func TestSlotsHeavyBlocks(t *testing.T) {
+	p := workerpool.New(4, workerpool.WithSlots(4))
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string) {
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+	}
+
+	b := newBlocker()
+	p.Submit(func(ctx context.Context) error {
+		record("light")
+		return b.task(ctx)
+	})
+	<-b.started
+	p.SubmitWeighted(func(context.Context) error {
+		record("heavy")
+		return nil
+	}, 4)
+	p.Submit(func(context.Context) error {
+		record("behind")
+		return nil
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	if s := p.Stats(); s.SlotsUsed != 1 || s.Busy != 1 {
+		t.Errorf("SlotsUsed = %d, Busy = %d while the heavy task waits, want 1 and 1", s.SlotsUsed, s.Busy)
+	}
+
+	close(b.release)
+	p.Shutdown()
+	if want := []string{"light", "heavy", "behind"}; !slices.Equal(order, want) {
+		t.Errorf("tasks ran in order %v, want %v", order, want)
+	}
+}
+
+func TestSlotsBudget(t *testing.T) {
+	const slots = 5
+	p := workerpool.New(8, workerpool.WithSlots(slots))
+
+	var (
+		mu         sync.Mutex
+		used, most int
+	)
+	for i := range 20 {
+		weight := 1 + i%3
+		p.SubmitWeighted(func(context.Context) error {
+			mu.Lock()
+			used += weight
+			most = max(most, used)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			used -= weight
+			mu.Unlock()
+			return nil
+		}, weight)
+	}
+	p.Shutdown()
+	if most > slots {
+		t.Errorf("running tasks weighed up to %d, want at most %d", most, slots)
+	}
+}
+
+func TestSlotsTooHeavy(t *testing.T) {
+	p := workerpool.New(1, workerpool.WithSlots(2))
+	defer p.Shutdown()
+	if err := p.SubmitWeighted(func(context.Context) error { return nil }, 3); !errors.Is(err, workerpool.ErrTooHeavy) {
+		t.Errorf("SubmitWeighted over the budget = %v, want ErrTooHeavy", err)
+	}
+}
@@ -0,0 +1,56 @@
+package workerpool
+
+import "time"
+
+// Stats is a snapshot of a pool's state and counters. The counters only go
+// up, so rates are the difference between two snapshots.
+type Stats struct {
+	Workers int // workers the pool is sized for
+	Busy    int // workers running a task
+	Queued  int // tasks waiting for a worker
+
+	// Reserved is the number of workers kept for urgent jobs by
+	// WithReservedWorkers, and ReservedBusy how many of them are in
+	// use: how far the urgent jobs running reach into the reserve.
+	Reserved     int
+	ReservedBusy int
+
+	// Slots is the slot budget given by WithSlots, and SlotsUsed how
+	// much of it the running tasks take.
+	Slots     int
+	SlotsUsed int
+
+	Submitted uint64 // tasks accepted by Submit
+	Completed uint64 // tasks that ran to completion, successfully or not
+	Failed    uint64 // completed tasks that returned an error or panicked
+	Dropped   uint64 // tasks discarded from the queue without running
+	Retried   uint64 // failed runs that were scheduled to run again
+
+	// WaitTime and RunTime are the total time tasks spent queued and
+	// running, counting every attempt.
+	WaitTime time.Duration
+	RunTime  time.Duration
+
+	// CancelToIdle is how long the pool took, once its context was
+	// cancelled or it was aborted, to discard its queue and see its
+	// running tasks return; zero until then, and after Shutdown.
+	CancelToIdle time.Duration
+}
+
+// Stats returns a snapshot of the pool's current state.
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.stats
+	s.Workers = p.size
+	s.Busy = p.busy
+	s.Queued = p.queue.Len()
+	s.Reserved = min(p.reserved, p.size)
+	s.ReservedBusy = min(max(p.busy-(p.size-p.reserved), 0), s.Reserved)
+	if p.slots > 0 {
+		s.Slots = p.slots
+		s.SlotsUsed = p.taken
+	}
+	return s
+}
@@ -0,0 +1,82 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+var errReread = errors.New("workerpool: reader cannot be rewound to run the task again")
+
+// SubmitReader queues fn to run with r as its input, for payloads too big
+// to hold in memory: only r waits in the queue, and the data is read on
+// the worker as fn needs it.
+//
+// The pool owns r from the call on and closes it exactly once, ignoring
+// the error: after fn's last run, when the task leaves the queue without
+// running, or straight away if SubmitReader returns an error. If the
+// task's context is done while fn runs, r is closed at once, so that a
+// Read blocked on a slow source returns instead of the worker waiting for
+// data nobody wants. fn must not close r or keep it after returning.
+//
+// Under WithRetry, r is rewound to where it stood when submitted before
+// each further run if it is an io.Seeker. Any other reader has been partly
+// read by then, so the task is not retried.
+func (p *Pool) SubmitReader(r io.ReadCloser, fn func(ctx context.Context, r io.Reader) error) error {
+	return p.SubmitReaderContext(context.Background(), r, fn)
+}
+
+// SubmitReaderContext is SubmitReader with the task tied to ctx, as with
+// SubmitContext.
+func (p *Pool) SubmitReaderContext(ctx context.Context, r io.ReadCloser, fn func(ctx context.Context, r io.Reader) error) error {
+	s := &stream{r: r}
+	if seeker, ok := r.(io.Seeker); ok {
+		if at, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			s.rewind = func() error {
+				_, err := seeker.Seek(at, io.SeekStart)
+				return err
+			}
+		}
+	}
+
+	err := p.enqueue(&Job{
+		task:   s.run(fn),
+		ctx:    ctx,
+		finish: func(error) { s.close() },
+		once:   s.rewind == nil,
+	})
+	if err != nil {
+		s.close()
+	}
+	return err
+}
+
+// stream is the reader of a task submitted with SubmitReader.
+type stream struct {
+	r      io.ReadCloser
+	rewind func() error // nil if r cannot be rewound
+	runs   int
+	closed sync.Once
+}
+
+func (s *stream) run(fn func(ctx context.Context, r io.Reader) error) Task {
+	return func(ctx context.Context) error {
+		if s.runs++; s.runs > 1 {
+			// Run again from a dead letter, say, if not by a retry.
+			if s.rewind == nil {
+				return errReread
+			}
+			if err := s.rewind(); err != nil {
+				return err
+			}
+		}
+		stop := context.AfterFunc(ctx, s.close)
+		defer stop()
+		return fn(ctx, s.r)
+	}
+}
+
+func (s *stream) close() {
+	s.closed.Do(func() { s.r.Close() })
+}
@@ -0,0 +1,13 @@
+package workerpool
+
+import "context"
+
+// Submitter is the part of a Pool that producers need. Code that only hands
+// work off should accept a Submitter, so tests can pass a recorder (see
+// pooltest) and cross-cutting behaviour can be layered on by wrapping.
+type Submitter interface {
+	Submit(task Task) error
+	SubmitContext(ctx context.Context, task Task) error
+}
+
+var _ Submitter = (*Pool)(nil)
@@ -0,0 +1,38 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrTaskTimeout is the error of a task that overran the timeout given to
+// SubmitWithTimeout.
+var ErrTaskTimeout = errors.New("workerpool: task timed out")
+
+// SubmitWithTimeout is like Submit, but the task's context gets a deadline d
+// after the task starts running; time spent queued doesn't count. If the
+// deadline passes before the task returns, its error is ErrTaskTimeout,
+// joined with whatever else the task returned besides the deadline error.
+//
+// A task that ignores its context still occupies its worker until it
+// returns; the timeout only tells it to stop.
+func (p *Pool) SubmitWithTimeout(task Task, d time.Duration) error {
+	return p.Submit(withTimeout(task, d))
+}
+
+func withTimeout(task Task, d time.Duration) Task {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeoutCause(ctx, d, ErrTaskTimeout)
+		defer cancel()
+
+		err := task(ctx)
+		if context.Cause(ctx) != ErrTaskTimeout {
+			return err
+		}
+		if err == nil || errors.Is(err, context.DeadlineExceeded) {
+			return ErrTaskTimeout
+		}
+		return errors.Join(ErrTaskTimeout, err)
+	}
+}
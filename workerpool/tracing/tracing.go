@@ -0,0 +1,111 @@
+// Package tracing gives tasks OpenTelemetry spans. It is a module of its
+// own so that the pool itself does not depend on OpenTelemetry.
+//
+// Trace is a decorate.Decorator, so only tasks submitted through the
+// Submitter it wraps are traced:
+//
+//	s := decorate.Chain(pool, tracing.Trace(otel.Tracer("thumbnail"), ""))
+//	s.SubmitContext(r.Context(), task)
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"workerpool"
+	"workerpool/decorate"
+)
+
+// Attributes set on every task span.
+const (
+	WorkerID   = attribute.Key("workerpool.worker.id")
+	WorkerName = attribute.Key("workerpool.worker.name")
+	QueueWait  = attribute.Key("workerpool.queue.wait") // seconds
+	Attempt    = attribute.Key("workerpool.attempt")    // 1 for the first run
+)
+
+// Trace runs each task in a span of its own, started by tracer under the
+// given name, or "workerpool.task" if name is empty. The span is a child
+// of the span in the submitting context, even if the pool does not pass
+// that context on to the task, and is in the task's context for the task
+// to add to.
+//
+// The span covers one run of the task and records the worker it ran on,
+// how long it waited in the queue beforehand, and its error or panic. Each
+// retry under workerpool.WithRetry gets a span of its own linked to the
+// first run's, with the wait counted from the end of the previous run.
+// Tasks dropped without running get no span.
+func Trace(tracer trace.Tracer, name string) decorate.Decorator {
+	if name == "" {
+		name = "workerpool.task"
+	}
+	return func(next workerpool.Submitter) workerpool.Submitter {
+		return decorate.Func(func(ctx context.Context, task workerpool.Task) error {
+			t := &traced{
+				tracer: tracer,
+				name:   name,
+				parent: trace.SpanFromContext(ctx),
+				last:   time.Now(),
+			}
+			return next.SubmitContext(ctx, t.run(task))
+		})
+	}
+}
+
+// traced is the tracing state of one submitted task.
+type traced struct {
+	tracer trace.Tracer
+	name   string
+	parent trace.Span
+
+	// The pool only retries a task once its run has returned, so runs
+	// never overlap and need no lock.
+	last    time.Time // when the task was queued or last returned
+	attempt int
+	first   trace.SpanContext
+}
+
+func (t *traced) run(task workerpool.Task) workerpool.Task {
+	return func(ctx context.Context) (err error) {
+		start := time.Now()
+		t.attempt++
+
+		opts := []trace.SpanStartOption{
+			trace.WithTimestamp(start),
+			trace.WithAttributes(
+				WorkerID.Int(workerpool.WorkerID(ctx)),
+				WorkerName.String(workerpool.WorkerName(ctx)),
+				QueueWait.Float64(start.Sub(t.last).Seconds()),
+				Attempt.Int(t.attempt),
+			),
+		}
+		if t.first.IsValid() {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: t.first}))
+		}
+		ctx, span := t.tracer.Start(trace.ContextWithSpan(ctx, t.parent), t.name, opts...)
+		if t.attempt == 1 {
+			t.first = span.SpanContext()
+		}
+
+		returned := false
+		defer func() {
+			switch {
+			case !returned:
+				span.SetStatus(codes.Error, "task panicked")
+			case err != nil:
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+			t.last = time.Now()
+		}()
+
+		err = task(ctx)
+		returned = true
+		return err
+	}
+}
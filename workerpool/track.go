@@ -0,0 +1,279 @@
+package workerpool
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+// TaskID identifies a task accepted by a pool. IDs count up from 1 in the
+// order tasks are accepted, and are never reused by the same pool.
+type TaskID uint64
+
+// State is where a task is in its life; see Status.
+type State int
+
+const (
+	// Pending tasks are queued, or waiting to be retried.
+	Pending State = iota
+	// Running tasks are running on a worker.
+	Running
+	// Succeeded tasks returned nil.
+	Succeeded
+	// Failed tasks returned an error or panicked on their last attempt, or
+	// were dropped or cancelled before they could run.
+	Failed
+)
+
+func (s State) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Running:
+		return "running"
+	case Succeeded:
+		return "succeeded"
+	case Failed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// Status is a snapshot of what a pool knows about one task.
+type Status struct {
+	ID       TaskID
+	State    State
+	Attempts int   // runs started so far
+	Err      error // why the task failed, if it did
+
+	Submitted time.Time
+	Started   time.Time // when the latest run started; zero before the first
+	Finished  time.Time // zero until the task has succeeded or failed
+}
+
+// record is the status of a tracked task, and the job while it is still
+// in the pool.
+type record struct {
+	status  Status
+	job     *Job
+	changed chan struct{} // closed at the next change, if anyone asked
+}
+
+// changeLocked tells those waiting for it that r has changed. p.mu must be
+// held.
+func (r *record) changeLocked() {
+	if r.changed != nil {
+		close(r.changed)
+		r.changed = nil
+	}
+}
+
+// WithTracking makes the pool keep the Status of every task while it is
+// queued or running, along with the last history tasks to have finished,
+// so that it can be queried by Status and queued tasks cancelled by
+// Cancel. It panics if history is negative.
+func WithTracking(history int) Option {
+	if history < 0 {
+		panic("workerpool: tracking history must not be negative")
+	}
+	return func(c *config) {
+		c.tracking = true
+		c.history = history
+	}
+}
+
+// SubmitTracked is like SubmitContext, but also returns the ID of the task
+// for Status and Cancel.
+func (p *Pool) SubmitTracked(ctx context.Context, task Task) (TaskID, error) {
+	j := &Job{task: task, ctx: ctx}
+	if err := p.enqueue(j); err != nil {
+		return 0, err
+	}
+	return j.id, nil
+}
+
+// SubmitAll submits tasks tied to ctx, as SubmitTracked would one by one
+// but taking the pool's lock only once, for producers that have many to
+// hand over at a time. It returns the ID of each task accepted, and the
+// error for each one that wasn't, the submission of one having no bearing
+// on the next.
+func (p *Pool) SubmitAll(ctx context.Context, tasks []Task) ([]TaskID, []error) {
+	ids := make([]TaskID, len(tasks))
+	errs := make([]error, len(tasks))
+	var evicted []*Job
+
+	p.mu.Lock()
+	for i, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		j := &Job{task: task, ctx: ctx}
+		e, err := p.admitLocked(j)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		ids[i] = j.id
+		if e != nil && e.finish != nil {
+			evicted = append(evicted, e)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, j := range evicted {
+		j.finish(ErrQueueFull)
+	}
+	if p.debugging(DebugEvents) {
+		for i := range tasks {
+			p.event(ctx, "workerpool: task submitted", "task", ids[i], "err", errs[i])
+		}
+	}
+	return ids, errs
+}
+
+// Status returns the status of the task with the given ID. It reports
+// false for a pool without WithTracking, and for a task it no longer
+// remembers or never had.
+func (p *Pool) Status(id TaskID) (Status, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, ok := p.tracked[id]
+	if !ok {
+		return Status{}, false
+	}
+	return r.status, true
+}
+
+// StatusChanged is Status with a channel that is closed when the status
+// next changes, to wait on rather than poll. Changes in quick succession
+// may be seen as one. Once the task has succeeded or failed its status no
+// longer changes, and the channel is nil.
+func (p *Pool) StatusChanged(id TaskID) (s Status, changed <-chan struct{}, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, ok := p.tracked[id]
+	if !ok {
+		return Status{}, nil, false
+	}
+	if r.job == nil {
+		return r.status, nil, true
+	}
+	if r.changed == nil {
+		r.changed = make(chan struct{})
+	}
+	return r.status, r.changed, true
+}
+
+// Tasks returns the status of every task the pool is tracking, in the
+// order they were submitted, or nil for a pool without WithTracking.
+func (p *Pool) Tasks() []Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.tracked == nil {
+		return nil
+	}
+	tasks := make([]Status, 0, len(p.tracked))
+	for _, r := range p.tracked {
+		tasks = append(tasks, r.status)
+	}
+	slices.SortFunc(tasks, func(a, b Status) int { return cmp.Compare(a.ID, b.ID) })
+	return tasks
+}
+
+// Cancel takes the task with the given ID out of the queue, or stops it
+// from being retried, as if its context had been cancelled: the task fails
+// with context.Canceled without running. It reports false if the task is
+// running, has finished, or is not tracked; see WithTracking.
+func (p *Pool) Cancel(id TaskID) bool {
+	p.mu.Lock()
+	r, ok := p.tracked[id]
+	if !ok || r.status.State != Pending {
+		p.mu.Unlock()
+		return false
+	}
+	j := r.job
+	switch t, retrying := p.retries[j]; {
+	case retrying:
+		t.Stop()
+		delete(p.retries, j)
+		// A closed pool's idle workers may only be waiting for this.
+		p.cond.Broadcast()
+	case p.queue.Remove(j):
+		p.room.Signal()
+	default:
+		p.mu.Unlock()
+		return false
+	}
+	p.unwatchLocked(j)
+	p.droppedLocked(j, context.Canceled)
+	p.mu.Unlock()
+
+	if j.finish != nil {
+		j.finish(context.Canceled)
+	}
+	return true
+}
+
+// trackLocked gives j, just accepted, its ID, and starts its record if the
+// pool keeps them. p.mu must be held.
+func (p *Pool) trackLocked(j *Job) {
+	p.lastID++
+	j.id = p.lastID
+	if p.tracked == nil {
+		return
+	}
+	j.rec = &record{
+		status: Status{ID: j.id, State: Pending, Submitted: j.queued},
+		job:    j,
+	}
+	p.tracked[j.id] = j.rec
+}
+
+// runningLocked records that j has started a run. p.mu must be held.
+func (p *Pool) runningLocked(j *Job) {
+	if r := j.rec; r != nil {
+		r.status.State = Running
+		r.status.Attempts++
+		r.status.Started = time.Now()
+		r.changeLocked()
+	}
+}
+
+// pendingLocked records that j is waiting to be retried. p.mu must be
+// held.
+func (p *Pool) pendingLocked(j *Job) {
+	if r := j.rec; r != nil {
+		r.status.State = Pending
+		r.changeLocked()
+	}
+}
+
+// endLocked records that j has left the pool for good, with err, and
+// forgets the oldest finished task if there are more than the history
+// allows. p.mu must be held.
+func (p *Pool) endLocked(j *Job, err error) {
+	r := j.rec
+	if r == nil {
+		return
+	}
+	j.rec = nil
+	r.job = nil
+	r.status.State = Succeeded
+	if err != nil {
+		r.status.State = Failed
+		r.status.Err = err
+	}
+	r.status.Finished = time.Now()
+	r.changeLocked()
+
+	p.finished = append(p.finished, j.id)
+	if len(p.finished) > p.history {
+		delete(p.tracked, p.finished[0])
+		p.finished = p.finished[1:]
+	}
+}
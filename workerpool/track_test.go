@@ -0,0 +1,178 @@
+package workerpool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"workerpool"
+	"workerpool/backoff"
+)
+
+func TestStatus(t *testing.T) {
+	p := workerpool.New(1, workerpool.WithTracking(10))
+	defer p.Shutdown()
+	ctx := context.Background()
+
+	b := newBlocker()
+	running, _ := p.SubmitTracked(ctx, b.task)
+	<-b.started
+	queued, _ := p.SubmitTracked(ctx, func(context.Context) error { return nil })
+	failing, _ := p.SubmitTracked(ctx, func(context.Context) error { return errFlaky })
+
+	if s, ok := p.Status(running); !ok || s.State != workerpool.Running || s.Attempts != 1 || s.Started.IsZero() {
+		t.Errorf("Status(running) = %+v, %v, want running its first attempt", s, ok)
+	}
+	if s, ok := p.Status(queued); !ok || s.State != workerpool.Pending || s.Attempts != 0 {
+		t.Errorf("Status(queued) = %+v, %v, want pending", s, ok)
+	}
+
+	close(b.release)
+	p.Wait()
+	for _, id := range []workerpool.TaskID{running, queued} {
+		if s, _ := p.Status(id); s.State != workerpool.Succeeded || s.Finished.IsZero() {
+			t.Errorf("Status(%d) = %+v, want succeeded", id, s)
+		}
+	}
+	if s, _ := p.Status(failing); s.State != workerpool.Failed || !errors.Is(s.Err, errFlaky) {
+		t.Errorf("Status(failing) = %+v, want failed with %v", s, errFlaky)
+	}
+	if _, ok := p.Status(failing + 1); ok {
+		t.Error("Status of an ID never handed out reports true")
+	}
+}
+
+func TestStatusUntracked(t *testing.T) {
+	p := workerpool.New(1)
+	defer p.Shutdown()
+	id, _ := p.SubmitTracked(context.Background(), func(context.Context) error { return nil })
+	if _, ok := p.Status(id); ok {
+		t.Error("Status reports true without WithTracking")
+	}
+	if tasks := p.Tasks(); tasks != nil {
+		t.Errorf("Tasks = %v without WithTracking, want nil", tasks)
+	}
+}
+
+func TestStatusChanged(t *testing.T) {
+	p := workerpool.New(1, workerpool.WithTracking(10))
+	defer p.Shutdown()
+
+	b := newBlocker()
+	id, _ := p.SubmitTracked(context.Background(), b.task)
+	<-b.started
+
+	s, changed, ok := p.StatusChanged(id)
+	if !ok || s.State != workerpool.Running || changed == nil {
+		t.Fatalf("StatusChanged = %+v, %v, %v, want running with a channel", s, changed, ok)
+	}
+	select {
+	case <-changed:
+		t.Fatal("changed closed while the task still runs")
+	default:
+	}
+
+	close(b.release)
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("changed not closed when the task finished")
+	}
+	if s, changed, _ := p.StatusChanged(id); s.State != workerpool.Succeeded || changed != nil {
+		t.Errorf("StatusChanged = %+v, %v once finished, want succeeded and no channel", s, changed)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	t.Run("Queued", func(t *testing.T) {
+		p := workerpool.New(1, workerpool.WithTracking(10))
+		defer p.Shutdown()
+
+		b := newBlocker()
+		p.Submit(b.task)
+		<-b.started
+		ran := false
+		id, _ := p.SubmitTracked(context.Background(), func(context.Context) error {
+			ran = true
+			return nil
+		})
+
+		if !p.Cancel(id) {
+			t.Fatal("Cancel of a queued task = false")
+		}
+		if s, _ := p.Status(id); s.State != workerpool.Failed || !errors.Is(s.Err, context.Canceled) {
+			t.Errorf("Status = %+v, want failed with context.Canceled", s)
+		}
+		if p.Cancel(id) {
+			t.Error("Cancel of a cancelled task = true")
+		}
+		close(b.release)
+		p.Wait()
+		if ran {
+			t.Error("cancelled task ran")
+		}
+	})
+
+	t.Run("Retrying", func(t *testing.T) {
+		p := workerpool.New(1, workerpool.WithTracking(10), workerpool.WithRetry(3, backoff.Constant(time.Hour)))
+		defer p.Shutdown()
+
+		id, _ := p.SubmitTracked(context.Background(), func(context.Context) error { return errFlaky })
+		for {
+			s, changed, _ := p.StatusChanged(id)
+			if s.State == workerpool.Pending && s.Attempts == 1 {
+				break
+			}
+			<-changed
+		}
+
+		if !p.Cancel(id) {
+			t.Fatal("Cancel of a task waiting to be retried = false")
+		}
+		if s, _ := p.Status(id); s.State != workerpool.Failed || s.Attempts != 1 || !errors.Is(s.Err, context.Canceled) {
+			t.Errorf("Status = %+v, want failed with context.Canceled after one attempt", s)
+		}
+		wait(t, func() { p.Wait() })
+	})
+
+	t.Run("Running", func(t *testing.T) {
+		p := workerpool.New(1, workerpool.WithTracking(10))
+		defer p.Shutdown()
+
+		b := newBlocker()
+		id, _ := p.SubmitTracked(context.Background(), b.task)
+		<-b.started
+		if p.Cancel(id) {
+			t.Error("Cancel of a running task = true")
+		}
+		close(b.release)
+		p.Wait()
+		if s, _ := p.Status(id); s.State != workerpool.Succeeded {
+			t.Errorf("Status = %+v, want succeeded", s)
+		}
+		if p.Cancel(id) {
+			t.Error("Cancel of a finished task = true")
+		}
+	})
+}
+
+func TestTrackingNoHistory(t *testing.T) {
+	p := workerpool.New(1, workerpool.WithTracking(0))
+	defer p.Shutdown()
+
+	b := newBlocker()
+	id, _ := p.SubmitTracked(context.Background(), b.task)
+	<-b.started
+	if _, ok := p.Status(id); !ok {
+		t.Error("Status of a running task = false")
+	}
+	close(b.release)
+	p.Wait()
+	if _, ok := p.Status(id); ok {
+		t.Error("finished task still tracked with no history")
+	}
+	if tasks := p.Tasks(); len(tasks) != 0 {
+		t.Errorf("Tasks = %v, want none", tasks)
+	}
+}
@@ -0,0 +1,124 @@
+package typed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"workerpool"
+)
+
+// BatchFunc processes a batch of tasks and returns their results, one for
+// each task and in the same order.
+type BatchFunc[T, R any] func(ctx context.Context, tasks []T) []R
+
+// Batcher is like Pool, but it coalesces submitted values into batches and
+// hands each batch to a BatchFunc as a single pool task. When each value is
+// only a little work, this amortises the cost of queueing and dispatch
+// that the workerpool4 notes warn about, and lets fn use bulk APIs.
+type Batcher[T, R any] struct {
+	pool     *workerpool.Pool
+	fn       BatchFunc[T, R]
+	maxBatch int
+	maxDelay time.Duration
+	results  chan R
+	once     sync.Once
+
+	mu     sync.Mutex
+	batch  []T
+	timer  *time.Timer // flushes a partial batch after maxDelay
+	closed bool
+}
+
+// NewBatcher starts numWorkers workers running fn on batches of up to
+// maxBatch values. A batch is dispatched once it is full, or maxDelay after
+// its first value was submitted, whichever comes first; with maxDelay zero,
+// partial batches wait for Flush or Shutdown.
+func NewBatcher[T, R any](numWorkers, maxBatch int, maxDelay time.Duration, fn BatchFunc[T, R]) *Batcher[T, R] {
+	return NewBatcherWithContext(context.Background(), numWorkers, maxBatch, maxDelay, fn)
+}
+
+// NewBatcherWithContext is like NewBatcher, but the pool stops when ctx is
+// cancelled, as with workerpool.NewWithContext.
+func NewBatcherWithContext[T, R any](ctx context.Context, numWorkers, maxBatch int, maxDelay time.Duration, fn BatchFunc[T, R]) *Batcher[T, R] {
+	if maxBatch < 1 {
+		panic("typed: maxBatch must be at least 1")
+	}
+	return &Batcher[T, R]{
+		pool:     workerpool.NewWithContext(ctx, numWorkers),
+		fn:       fn,
+		maxBatch: maxBatch,
+		maxDelay: maxDelay,
+		results:  make(chan R, numWorkers*maxBatch),
+	}
+}
+
+// Submit adds task to the current batch. It returns workerpool.ErrClosed
+// after Shutdown, and the pool's error if a full batch can't be queued;
+// a batch flushed by the timer that can't be queued is dropped.
+func (b *Batcher[T, R]) Submit(task T) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return workerpool.ErrClosed
+	}
+	b.batch = append(b.batch, task)
+	if len(b.batch) >= b.maxBatch {
+		return b.flushLocked()
+	}
+	if b.timer == nil && b.maxDelay > 0 {
+		b.timer = time.AfterFunc(b.maxDelay, func() { b.Flush() })
+	}
+	return nil
+}
+
+// Flush dispatches the current batch without waiting for it to fill up.
+func (b *Batcher[T, R]) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked()
+}
+
+func (b *Batcher[T, R]) flushLocked() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.batch) == 0 {
+		return nil
+	}
+
+	batch := b.batch
+	b.batch = nil
+	return b.pool.Submit(func(ctx context.Context) error {
+		for _, r := range b.fn(ctx, batch) {
+			select {
+			case b.results <- r:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Results returns the channel results are delivered on: in order within a
+// batch, and in completion order across batches. It is closed by Shutdown
+// once every result has been delivered, and must be drained concurrently
+// with Submit and Shutdown.
+func (b *Batcher[T, R]) Results() <-chan R {
+	return b.results
+}
+
+// Shutdown dispatches the last, partial batch, stops accepting tasks, waits
+// for the batches to finish and closes the results channel.
+func (b *Batcher[T, R]) Shutdown() {
+	b.mu.Lock()
+	b.closed = true
+	b.flushLocked()
+	b.mu.Unlock()
+
+	b.pool.Shutdown()
+	b.once.Do(func() { close(b.results) })
+}
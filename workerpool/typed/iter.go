@@ -0,0 +1,50 @@
+package typed
+
+import (
+	"iter"
+
+	"workerpool"
+)
+
+// All returns an iterator over the results, in the order Results would
+// deliver them:
+//
+//	go func() {
+//		for _, t := range tasks {
+//			p.Submit(t)
+//		}
+//		p.Shutdown()
+//	}()
+//	for r, err := range p.All() {
+//		...
+//	}
+//
+// The iteration ends once Shutdown has been called and every result
+// delivered. If tasks panicked since the pool's last Wait, it ends with a
+// zero result and their errors, joined. Breaking out of the loop early
+// aborts the pool, cancelling the running tasks and discarding the queued
+// ones, so that nothing is left blocked on results nobody will read.
+func (p *Pool[T, R]) All() iter.Seq2[R, error] {
+	return all(p.pool, p.results, func() { p.once.Do(func() { close(p.results) }) })
+}
+
+// All is like Pool.All.
+func (b *Batcher[T, R]) All() iter.Seq2[R, error] {
+	return all(b.pool, b.results, func() { b.once.Do(func() { close(b.results) }) })
+}
+
+func all[R any](pool *workerpool.Pool, results <-chan R, closeResults func()) iter.Seq2[R, error] {
+	return func(yield func(R, error) bool) {
+		for r := range results {
+			if !yield(r, nil) {
+				pool.Abort()
+				closeResults()
+				return
+			}
+		}
+		if err := pool.Wait(); err != nil {
+			var zero R
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package typed
+
+import (
+	"context"
+	"sync"
+
+	"workerpool"
+)
+
+// reorder puts results back into submission order for an Ordered pool.
+type reorder[R any] struct {
+	results chan<- R
+
+	submitMu  sync.Mutex
+	submitted uint64 // sequence number of the next task
+
+	mu   sync.Mutex
+	next uint64 // sequence number of the next result to deliver
+	done map[uint64]outcome[R]
+}
+
+// outcome is a task's result, or the lack of one if it panicked.
+type outcome[R any] struct {
+	value R
+	ok    bool
+}
+
+func newReorder[R any](results chan<- R) *reorder[R] {
+	return &reorder[R]{results: results, done: make(map[uint64]outcome[R])}
+}
+
+// submit numbers the task computing a result and submits it to pool.
+// Numbers are handed out under a lock, and only to tasks the pool
+// accepted, so the sequence has no gaps.
+func (o *reorder[R]) submit(pool *workerpool.Pool, fn func(ctx context.Context) R) error {
+	o.submitMu.Lock()
+	defer o.submitMu.Unlock()
+
+	seq := o.submitted
+	err := pool.Submit(func(ctx context.Context) error {
+		var r outcome[R]
+		// Release even if fn panics, or every later result would wait
+		// forever for this one.
+		defer func() { o.release(ctx, seq, r) }()
+		r.value = fn(ctx)
+		r.ok = true
+		return nil
+	})
+	if err == nil {
+		o.submitted++
+	}
+	return err
+}
+
+// release records the outcome of task seq and delivers whatever results
+// are now next in line. The lock is held while delivering so results go
+// out in order; other workers finishing meanwhile wait for it, much as they
+// would wait for the results channel otherwise.
+func (o *reorder[R]) release(ctx context.Context, seq uint64, r outcome[R]) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.done[seq] = r
+	for {
+		r, ok := o.done[o.next]
+		if !ok {
+			return
+		}
+		delete(o.done, o.next)
+		o.next++
+		if !r.ok {
+			continue
+		}
+		select {
+		case o.results <- r.value:
+		case <-ctx.Done():
+		}
+	}
+}
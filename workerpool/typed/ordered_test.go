@@ -0,0 +1,68 @@
+package typed_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"workerpool/typed"
+)
+
+// collect submits tasks to p and returns the results in the order they
+// were delivered.
+func collect(t *testing.T, p *typed.Pool[int, int], tasks []int) []int {
+	t.Helper()
+	got := make(chan []int)
+	go func() {
+		var rs []int
+		for r := range p.Results() {
+			rs = append(rs, r)
+		}
+		got <- rs
+	}()
+	for _, task := range tasks {
+		if err := p.Submit(task); err != nil {
+			t.Fatalf("Submit(%d) = %v", task, err)
+		}
+	}
+	p.Shutdown()
+	return <-got
+}
+
+func TestOrdered(t *testing.T) {
+	// Later tasks finish first.
+	p := typed.New(4, func(_ context.Context, n int) int {
+		time.Sleep(time.Duration(10-n) * time.Millisecond)
+		return n * n
+	}, typed.Ordered())
+
+	got := collect(t, p, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	if want := []int{0, 1, 4, 9, 16, 25, 36, 49, 64, 81}; !slices.Equal(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestOrderedSkipsPanics(t *testing.T) {
+	p := typed.New(2, func(_ context.Context, n int) int {
+		if n%3 == 0 {
+			panic("no result")
+		}
+		return n
+	}, typed.Ordered())
+
+	got := collect(t, p, []int{1, 2, 3, 4, 5, 6, 7})
+	if want := []int{1, 2, 4, 5, 7}; !slices.Equal(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+func TestUnordered(t *testing.T) {
+	p := typed.New(4, func(_ context.Context, n int) int { return -n })
+
+	got := collect(t, p, []int{1, 2, 3, 4, 5})
+	slices.Sort(got)
+	if want := []int{-5, -4, -3, -2, -1}; !slices.Equal(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
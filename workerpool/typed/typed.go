@@ -0,0 +1,105 @@
+// Package typed layers typed tasks and results over workerpool.Pool, so
+// callers don't have to write a one-off pool per element type or cast
+// through interface{}.
+package typed
+
+import (
+	"context"
+	"sync"
+
+	"workerpool"
+)
+
+// Func processes a single task and returns its result.
+type Func[T, R any] func(ctx context.Context, task T) R
+
+// Pool feeds submitted values of type T to a Func on a fixed number of
+// workers and delivers the results on a channel.
+type Pool[T, R any] struct {
+	pool    *workerpool.Pool
+	fn      Func[T, R]
+	results chan R
+	once    sync.Once
+	order   *reorder[R] // nil unless Ordered
+}
+
+// Option configures a Pool.
+type Option func(*options)
+
+type options struct {
+	ordered bool
+}
+
+// Ordered makes the results channel deliver results in the order their
+// tasks were submitted, rather than as they complete. A result that is
+// ready early is held back until those before it have been delivered, so
+// one slow task delays all the results after it; with no result to give, a
+// task that panicked is skipped.
+func Ordered() Option {
+	return func(o *options) { o.ordered = true }
+}
+
+// New starts numWorkers workers running fn.
+func New[T, R any](numWorkers int, fn Func[T, R], opts ...Option) *Pool[T, R] {
+	return NewWithContext(context.Background(), numWorkers, fn, opts...)
+}
+
+// NewWithContext is like New, but the pool stops when ctx is cancelled, as
+// with workerpool.NewWithContext. Results of tasks finishing after that are
+// dropped.
+func NewWithContext[T, R any](ctx context.Context, numWorkers int, fn Func[T, R], opts ...Option) *Pool[T, R] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	p := &Pool[T, R]{
+		pool:    workerpool.NewWithContext(ctx, numWorkers),
+		fn:      fn,
+		results: make(chan R, numWorkers),
+	}
+	if o.ordered {
+		p.order = newReorder(p.results)
+	}
+	return p
+}
+
+// Submit queues task for the next free worker. It returns the same errors as
+// workerpool.Pool.Submit.
+func (p *Pool[T, R]) Submit(task T) error {
+	if p.order != nil {
+		return p.order.submit(p.pool, func(ctx context.Context) R {
+			return p.fn(ctx, task)
+		})
+	}
+	return p.pool.Submit(func(ctx context.Context) error {
+		r := p.fn(ctx, task)
+		select {
+		case p.results <- r:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+}
+
+// SubmitFuture is like Submit, but the result is delivered through the
+// returned Future instead of the results channel.
+func (p *Pool[T, R]) SubmitFuture(task T) *workerpool.Future[R] {
+	return workerpool.SubmitFuture(p.pool, func(ctx context.Context) (R, error) {
+		return p.fn(ctx, task), nil
+	})
+}
+
+// Results returns the channel results are delivered on, in completion order
+// unless the pool is Ordered. It is closed by Shutdown once every result has
+// been delivered. Results must be drained concurrently with Submit and
+// Shutdown: workers block until their result is taken.
+func (p *Pool[T, R]) Results() <-chan R {
+	return p.results
+}
+
+// Shutdown stops accepting tasks, waits for the submitted ones to finish and
+// closes the results channel.
+func (p *Pool[T, R]) Shutdown() {
+	p.pool.Shutdown()
+	p.once.Do(func() { close(p.results) })
+}
@@ -0,0 +1,103 @@
+package workerpool
+
+import "context"
+
+// watch is the one context.AfterFunc shared by all the queued or retrying
+// jobs whose contexts have the same done channel. Cancelling a context then
+// costs one callback and one sweep however many jobs were submitted with
+// it, instead of a callback, a goroutine and a trip through the pool's lock
+// per job.
+type watch struct {
+	done <-chan struct{}
+	stop func() bool
+	jobs []*Job // each at its watchIndex
+}
+
+// watchLocked arranges for j to be swept out of the pool if its context is
+// cancelled before a worker gets to it. p.mu must be held.
+func (p *Pool) watchLocked(j *Job) {
+	done := j.ctx.Done()
+	if done == nil {
+		return // never cancelled
+	}
+	w := p.watches[done]
+	if w == nil {
+		w = &watch{done: done}
+		w.stop = context.AfterFunc(j.ctx, func() { p.sweep(w) })
+		p.watches[done] = w
+	}
+	j.watch = w
+	j.watchIndex = len(w.jobs)
+	w.jobs = append(w.jobs, j)
+}
+
+// unwatchLocked undoes watchLocked once j has left the queue by other
+// means. p.mu must be held.
+func (p *Pool) unwatchLocked(j *Job) {
+	w := j.watch
+	if w == nil {
+		return
+	}
+	j.watch = nil
+	last := w.jobs[len(w.jobs)-1]
+	w.jobs[j.watchIndex] = last
+	last.watchIndex = j.watchIndex
+	w.jobs[len(w.jobs)-1] = nil
+	w.jobs = w.jobs[:len(w.jobs)-1]
+	if len(w.jobs) == 0 {
+		w.stop()
+		if p.watches[w.done] == w {
+			delete(p.watches, w.done)
+		}
+	}
+}
+
+// sweep drops the jobs of w, whose context has been cancelled.
+func (p *Pool) sweep(w *watch) {
+	var finish []*Job
+
+	p.mu.Lock()
+	retrying := 0
+	if len(p.retries) > 0 {
+		for _, j := range w.jobs {
+			if _, ok := p.retries[j]; ok {
+				retrying++
+			}
+		}
+	}
+	queued := len(w.jobs) - retrying
+	// If everything queued is ours, empty the queue in one go rather than
+	// removing jobs one at a time.
+	whole := queued > 1 && queued == p.queue.Len()
+	if whole {
+		p.clearLocked()
+	}
+	for _, j := range w.jobs {
+		j.watch = nil
+		if t, ok := p.retries[j]; ok {
+			t.Stop()
+			delete(p.retries, j)
+		} else if !whole {
+			p.queue.Remove(j)
+		}
+		if j.finish != nil {
+			finish = append(finish, j)
+		}
+		p.droppedLocked(j, j.ctx.Err())
+	}
+	w.jobs = nil
+	if p.watches[w.done] == w {
+		delete(p.watches, w.done)
+	}
+	if retrying > 0 {
+		p.cond.Broadcast()
+	}
+	if queued > 0 {
+		p.room.Broadcast()
+	}
+	p.mu.Unlock()
+
+	for _, j := range finish {
+		j.finish(j.ctx.Err())
+	}
+}
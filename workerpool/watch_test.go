@@ -0,0 +1,62 @@
+package workerpool_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"workerpool"
+)
+
+func TestCancelQueuedJob(t *testing.T) {
+	p := workerpool.New(1)
+	defer p.Shutdown()
+
+	release := make(chan struct{})
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	defer unblock()
+	started := make(chan struct{})
+	p.Submit(func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{})
+	if err := p.SubmitContext(ctx, func(context.Context) error {
+		close(ran)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); s.Queued != 1 {
+		t.Fatalf("Queued = %d before cancelling, want 1", s.Queued)
+	}
+
+	// The only worker is still busy, so the job must be swept out of the
+	// queue by the cancellation itself.
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s := p.Stats()
+		if s.Queued == 0 && s.Dropped == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cancelled job still counted: Queued = %d, Dropped = %d", s.Queued, s.Dropped)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	unblock()
+	p.Shutdown()
+	select {
+	case <-ran:
+		t.Error("the cancelled job ran")
+	default:
+	}
+}